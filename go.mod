@@ -8,16 +8,16 @@ require (
 	github.com/andybalholm/cascadia v1.2.0 // indirect
 	github.com/dghubble/go-twitter v0.0.0-20201011215211-4b180d0cc78d
 	github.com/dyatlov/go-htmlinfo v0.0.0-20180517114536-d9417c75de65
-	github.com/dyatlov/go-oembed v0.0.0-20191103150536-a57c85b3b37c // indirect
+	github.com/dyatlov/go-oembed v0.0.0-20191103150536-a57c85b3b37c
 	github.com/dyatlov/go-opengraph v0.0.0-20180429202543-816b6608b3c8 // indirect
-	github.com/dyatlov/go-readability v0.0.0-20150926130635-e7b2080f87f8 // indirect
+	github.com/dyatlov/go-readability v0.0.0-20150926130635-e7b2080f87f8
 	github.com/monstercat/golib v0.0.0-20201109195614-7ff748c92660
 	github.com/pkg/errors v0.9.1
 	github.com/tidwall/gjson v1.6.1 // indirect
 	golang.org/x/net v0.0.0-20201109172640-a11eb1b685be
 	golang.org/x/oauth2 v0.0.0-20201109201403-9fd604954f58
 	golang.org/x/sys v0.0.0-20201109165425-215b40eba54c // indirect
-	golang.org/x/text v0.3.4 // indirect
+	golang.org/x/text v0.3.4
 	google.golang.org/api v0.35.0
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/genproto v0.0.0-20201109203340-2640f1f9cdfb // indirect
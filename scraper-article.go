@@ -0,0 +1,94 @@
+package vinscraper
+
+import (
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dyatlov/go-readability"
+	"golang.org/x/net/html"
+)
+
+// Roughly how fast adults read, used for ReadingTime
+const ArticleWordsPerMinute = 230
+
+// The main text of a page, found with readability. Only there when ScraperGeneric.ExtractArticle is on
+type GenericArticle struct {
+	Text        string // Paragraphs separated by blank lines
+	WordCount   int
+	ReadingTime time.Duration // Rounded up to the minute
+	LeadImage   string        // The first image in the article
+}
+
+// Returns nil if readability couldn't find anything that looks like an article
+func extractArticle(page string, base *url.URL) *GenericArticle {
+	doc, err := readability.NewDocument(page)
+	if err != nil {
+		return nil
+	}
+	doc.WhitelistTags = []string{"div", "p", "img"}
+	doc.WhitelistAttrs["img"] = []string{"src", "alt"}
+
+	content, err := html.Parse(strings.NewReader(doc.Content()))
+	if err != nil {
+		return nil
+	}
+
+	article := &GenericArticle{}
+	paragraphs := articleParagraphs(content)
+	for _, p := range paragraphs {
+		article.WordCount += len(strings.Fields(p))
+	}
+	if article.WordCount == 0 {
+		return nil
+	}
+	article.Text = strings.Join(paragraphs, "\n\n")
+	minutes := math.Ceil(float64(article.WordCount) / ArticleWordsPerMinute)
+	article.ReadingTime = time.Duration(minutes) * time.Minute
+
+	walkHTML(content, func(n *html.Node) bool {
+		if n.Data == "img" && article.LeadImage == "" {
+			article.LeadImage = resolveURL(base, htmlAttr(n, "src"))
+		}
+		return article.LeadImage == ""
+	})
+	return article
+}
+
+// Readability leaves the text in <p>s and <div>s, each of those is a paragraph
+func articleParagraphs(n *html.Node) []string {
+	var paragraphs []string
+	var current strings.Builder
+	flush := func() {
+		if p := normalizeText(current.String()); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+		current.Reset()
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			current.WriteString(n.Data)
+			return
+		}
+		block := n.Type == html.ElementNode && (n.Data == "p" || n.Data == "div")
+		if block {
+			flush()
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			flush()
+		}
+	}
+	walk(n)
+	flush()
+	return paragraphs
+}
+
+func (a *GenericArticle) FirstParagraph() string {
+	return strings.SplitN(a.Text, "\n\n", 2)[0]
+}
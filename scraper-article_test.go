@@ -0,0 +1,63 @@
+package vinscraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScrapeGenericArticle(t *testing.T) {
+	first := "Spirit Island is a cooperative game where the players are spirits defending their island from colonizing invaders."
+	// 10 words, 50 times over is 500 words
+	filler := strings.Repeat("The invaders explore, build and ravage the island every turn. ", 50)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Spirit Island Review</title></head><body>
+<div class="nav"><a href="/">Home</a> <a href="/reviews">Reviews</a></div>
+<div class="article-content">
+	<p>` + first + `</p>
+	<img src="/images/spirit-island.jpg" alt="The board">
+	<p>` + filler + `</p>
+</div>
+<div class="footer">Copyright</div>
+</body></html>`))
+	}))
+	defer server.Close()
+
+	info, err := (&ScraperGeneric{ExtractArticle: true}).Scrape(server.URL + "/reviews/spirit-island")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Description != first {
+		t.Errorf("Expected the first paragraph as the description but got '%s'", info.Description)
+	}
+
+	article := info.Meta.(*GenericPageMeta).Article
+	if article == nil {
+		t.Fatal("Expected an article")
+	}
+	if article.WordCount != 517 {
+		t.Errorf("Expected 517 words but got %d", article.WordCount)
+	}
+	if article.ReadingTime != 3*time.Minute {
+		t.Errorf("Expected 3 minutes to read but got %s", article.ReadingTime)
+	}
+	if article.LeadImage != server.URL+"/images/spirit-island.jpg" {
+		t.Errorf("Expected the lead image to be resolved but got '%s'", article.LeadImage)
+	}
+	if strings.Contains(article.Text, "Copyright") || !strings.HasPrefix(article.Text, first+"\n\n") {
+		t.Errorf("Expected only the article's paragraphs but got '%.100s...'", article.Text)
+	}
+
+	// Off by default
+	info, err = (&ScraperGeneric{}).Scrape(server.URL + "/reviews/spirit-island")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Meta.(*GenericPageMeta).Article != nil || info.Description != "" {
+		t.Errorf("Expected no article without ExtractArticle")
+	}
+}
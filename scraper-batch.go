@@ -0,0 +1,225 @@
+package vinscraper
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultConcurrency     = 8
+	DefaultHostConcurrency = 2
+)
+
+// The outcome of scraping one link in a batch
+type ScrapeResult struct {
+	Index int // Position of the link in the input
+	URL   string
+	Info  *ScrapeInfo
+	Err   error
+}
+
+// Scrapers that merge lookups running at the same time into one request, like RedditScraper
+// with a BatchWindow. BatchSize is how many of them can share a request, 0 if link isn't batched
+// Up to that many of a batching scraper's links run at once in ScrapeBatch and ScrapeStream,
+// without taking up a worker or counting towards HostConcurrency
+type BatchingScraper interface {
+	BatchSize(link string) int
+}
+
+// Limits how many scrapes can hit the same host at once
+// It lives on Scraping so every batch running on it shares the same limits
+type hostSemaphores struct {
+	limit int
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func (h *hostSemaphores) acquire(ctx context.Context, host string) error {
+	return h.acquireLimit(ctx, host, h.limit)
+}
+
+// Like acquire with its own limit, which is set the first time key is used
+func (h *hostSemaphores) acquireLimit(ctx context.Context, key string, limit int) error {
+	h.mu.Lock()
+	if h.slots == nil {
+		h.slots = make(map[string]chan struct{})
+	}
+	slot, ok := h.slots[key]
+	if !ok {
+		slot = make(chan struct{}, limit)
+		h.slots[key] = slot
+	}
+	h.mu.Unlock()
+
+	select {
+	case slot <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *hostSemaphores) release(host string) {
+	h.mu.Lock()
+	slot := h.slots[host]
+	h.mu.Unlock()
+	<-slot
+}
+
+// www.reddit.com and reddit.com are the same host as far as limits go
+func batchHost(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// How many links can share a request with link, 0 if the scraper that would handle it doesn't batch
+func (s *Scraping) batchSize(link string) int {
+	for _, r := range s.EnabledScrapers() {
+		if r.Scraper.WantsURL(link) {
+			if b, ok := r.Scraper.(BatchingScraper); ok {
+				return b.BatchSize(link)
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+func (s *Scraping) hostLimits() *hostSemaphores {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hostSlots == nil {
+		limit := s.HostConcurrency
+		if limit <= 0 {
+			limit = DefaultHostConcurrency
+		}
+		s.hostSlots = &hostSemaphores{limit: limit}
+	}
+	return s.hostSlots
+}
+
+// Scrapes every link and returns the results in the same order as the links
+// Each result has its own error, one link failing does not stop the others
+func (s *Scraping) ScrapeBatch(ctx context.Context, links []string) []ScrapeResult {
+	in := make(chan string)
+	go func() {
+		defer close(in)
+		for _, link := range links {
+			select {
+			case in <- link:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make([]ScrapeResult, len(links))
+	filled := make([]bool, len(links))
+	for r := range s.ScrapeStream(ctx, in) {
+		results[r.Index] = r
+		filled[r.Index] = true
+	}
+
+	// Links we never got to because the context finished first
+	for i, ok := range filled {
+		if !ok {
+			results[i] = ScrapeResult{Index: i, URL: links[i], Err: ctx.Err()}
+		}
+	}
+	return results
+}
+
+// Scrapes links as they come in on the channel and sends each result out as soon as it's done
+// Results are not in order, use ScrapeResult.Index to match them up
+// The returned channel is closed once links is closed and everything in flight has finished
+// so keep reading from it until then, or until ctx is done. After that no more links are taken
+// and results nobody reads are dropped
+func (s *Scraping) ScrapeStream(ctx context.Context, links <-chan string) <-chan ScrapeResult {
+	workers := s.Concurrency
+	if workers <= 0 {
+		workers = DefaultConcurrency
+	}
+	hosts := s.hostLimits()
+
+	type job struct {
+		index int
+		link  string
+	}
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		for i := 0; ; i++ {
+			select {
+			case link, ok := <-links:
+				if !ok {
+					return
+				}
+				select {
+				case jobs <- job{i, link}:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	out := make(chan ScrapeResult)
+	send := func(result ScrapeResult) {
+		select {
+		case out <- result:
+		case <-ctx.Done():
+		}
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				result := ScrapeResult{Index: j.index, URL: j.link}
+				host := batchHost(j.link)
+
+				// Batched links are left to wait on their batch so the worker can move on
+				// and the batch can fill up
+				if size := s.batchSize(j.link); size > 0 {
+					key := "batch:" + host
+					if err := hosts.acquireLimit(ctx, key, size); err != nil {
+						result.Err = err
+						send(result)
+						continue
+					}
+					wg.Add(1)
+					go func(result ScrapeResult) {
+						defer wg.Done()
+						result.Info, result.Err = s.ScrapeContext(ctx, result.URL)
+						hosts.release(key)
+						send(result)
+					}(result)
+					continue
+				}
+
+				if err := hosts.acquire(ctx, host); err != nil {
+					result.Err = err
+				} else {
+					result.Info, result.Err = s.ScrapeContext(ctx, j.link)
+					hosts.release(host)
+				}
+				send(result)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
@@ -0,0 +1,144 @@
+package vinscraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Records how many scrapes it's running at once per host
+type concurrencyScraper struct {
+	mu      sync.Mutex
+	running map[string]int
+	peak    map[string]int
+}
+
+func (c *concurrencyScraper) WantsURL(link string) bool {
+	return true
+}
+
+func (c *concurrencyScraper) Scrape(link string) (*ScrapeInfo, error) {
+	host := batchHost(link)
+	c.mu.Lock()
+	c.running[host]++
+	if c.running[host] > c.peak[host] {
+		c.peak[host] = c.running[host]
+	}
+	c.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	c.mu.Lock()
+	c.running[host]--
+	c.mu.Unlock()
+
+	if strings.Contains(link, "broken") {
+		return nil, errors.New("broken link")
+	}
+	return &ScrapeInfo{Title: link}, nil
+}
+
+func TestScrapeBatch(t *testing.T) {
+	scraper := &concurrencyScraper{
+		running: map[string]int{},
+		peak:    map[string]int{},
+	}
+	scraping := &Scraping{
+		Concurrency:     6,
+		HostConcurrency: 2,
+		Scrapers:        []Scraper{scraper},
+	}
+
+	links := make([]string, 0)
+	for i := 0; i < 10; i++ {
+		links = append(links, fmt.Sprintf("https://www.reddit.com/%d", i))
+		links = append(links, fmt.Sprintf("https://example.com/%d", i))
+	}
+	links = append(links, "https://example.com/broken")
+
+	results := scraping.ScrapeBatch(context.Background(), links)
+	if len(results) != len(links) {
+		t.Fatalf("Expected %d results but got %d", len(links), len(results))
+	}
+	for i, r := range results {
+		if r.URL != links[i] || r.Index != i {
+			t.Errorf("[%d] result is out of order: %d %s", i, r.Index, r.URL)
+		}
+		if r.URL == "https://example.com/broken" {
+			if r.Err == nil {
+				t.Errorf("[%d] expected an error", i)
+			}
+		} else if r.Err != nil || r.Info.Title != r.URL {
+			t.Errorf("[%d] unexpected result %v %v", i, r.Info, r.Err)
+		}
+	}
+
+	for host, peak := range scraper.peak {
+		if peak > 2 {
+			t.Errorf("%s had %d scrapes running at once", host, peak)
+		}
+	}
+}
+
+func TestScrapeBatchRedditBatching(t *testing.T) {
+	transport := &redditInfoTransport{}
+	scraping := &Scraping{
+		Scrapers: []Scraper{&RedditScraper{
+			HTTPClient:  &http.Client{Transport: transport},
+			BatchWindow: DefaultRedditBatchWindow,
+		}},
+	}
+
+	links := make([]string, 30)
+	for i := range links {
+		links[i] = fmt.Sprintf("https://www.reddit.com/comments/p%d/_/", i)
+	}
+	for i, r := range scraping.ScrapeBatch(context.Background(), links) {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		if title := fmt.Sprintf("Title p%d", i); r.Info.Title != title {
+			t.Errorf("[%d] expected '%s' but got '%s'", i, title, r.Info.Title)
+		}
+	}
+
+	// Neither Concurrency nor HostConcurrency split them up
+	if len(transport.calls) != 1 || len(transport.calls[0]) != len(links) {
+		t.Errorf("Expected 1 call for all %d posts but got %v", len(links), transport.calls)
+	}
+}
+
+func TestScrapeStreamCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	scraping := &Scraping{
+		Concurrency: 2,
+		Scrapers:    []Scraper{&staticScraper{ScrapeInfo{Title: "Page"}}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	links := make(chan string)
+	out := scraping.ScrapeStream(ctx, links)
+
+	links <- "https://example.com/1"
+	if r := <-out; r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	// More links finish but nobody reads them, and links is never closed
+	links <- "https://example.com/2"
+	links <- "https://example.com/3"
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("Expected the stream's goroutines to finish but %d are left over", n-before)
+	}
+}
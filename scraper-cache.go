@@ -0,0 +1,492 @@
+package vinscraper
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCacheTTL      = time.Hour
+	DefaultCacheStaleTTL = 24 * time.Hour
+	DefaultCacheErrorTTL = time.Minute
+
+	DefaultCacheRefreshTimeout = time.Minute
+)
+
+var (
+	ErrCachedFailure = errors.New("link failed to scrape recently")
+)
+
+type CacheEntry struct {
+	Info *ScrapeInfo
+	Err  string // Set instead of Info when the scrape failed
+	// What the failure's ScrapeError said, Err is then the message it wrapped
+	ErrKind   ErrorKind `json:",omitempty"`
+	ErrSource string    `json:",omitempty"`
+	ErrStatus int       `json:",omitempty"`
+	StoredAt  time.Time
+	ExpiresAt time.Time // Past this the entry is stale
+
+	// The error itself, only kept in memory so errors.Is still finds sentinels like ErrRedditNoChildren
+	err error
+}
+
+// What a fresh failed entry is served as
+// errors.Is matches ErrCachedFailure as well as whatever the original error did
+type cachedError struct {
+	msg string
+	err error
+}
+
+func (e *cachedError) Error() string {
+	return ErrCachedFailure.Error() + ": " + e.msg
+}
+
+func (e *cachedError) Is(target error) bool {
+	return target == ErrCachedFailure
+}
+
+func (e *cachedError) Unwrap() error {
+	return e.err
+}
+
+func (e *CacheEntry) error() error {
+	err := &cachedError{msg: e.Err, err: e.err}
+	if e.ErrKind == "" {
+		return err
+	}
+	return &ScrapeError{
+		Kind:   e.ErrKind,
+		Source: e.ErrSource,
+		Status: e.ErrStatus,
+		Err:    err,
+	}
+}
+
+func (e *CacheEntry) Fresh(now time.Time) bool {
+	return now.Before(e.ExpiresAt)
+}
+
+// Somewhere to keep scrape results so popular links aren't scraped over and over
+// Entries are stored past their expiry so they can be served stale, it's up to
+// Scraping to decide when an entry is too old and Delete it
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry) error
+	Delete(key string) error
+}
+
+// Scrapers that can tell a link's SourceType and SourceKey without fetching it
+// Their results are cached by source instead of by url, so every link to the same thing shares one entry
+type SourceIdentifier interface {
+	// ok is false if the link isn't one this scraper can identify
+	SourceOf(link string) (sourceType SourceType, sourceKey string, ok bool)
+}
+
+// The key for a result's SourceType+SourceKey, used for SourceIdentifier scrapers and by CachedSource
+func SourceCacheKey(sourceType SourceType, sourceKey string) string {
+	return "source:" + string(sourceType) + ":" + sourceKey
+}
+
+// Where link's result is kept, by source if its scraper can tell it, otherwise by standardized url
+func (s *Scraping) cacheKey(link string) string {
+	for _, r := range s.EnabledScrapers() {
+		if !r.Scraper.WantsURL(link) {
+			continue
+		}
+		if id, ok := r.Scraper.(SourceIdentifier); ok {
+			if sourceType, sourceKey, ok := id.SourceOf(link); ok {
+				return SourceCacheKey(sourceType, sourceKey)
+			}
+		}
+		break
+	}
+	return s.StandardizeURL(link)
+}
+
+func (s *Scraping) cacheTTL(info *ScrapeInfo) time.Duration {
+	if ttl, ok := s.CacheTTL[info.SourceType]; ok {
+		return ttl
+	}
+	if s.DefaultCacheTTL > 0 {
+		return s.DefaultCacheTTL
+	}
+	return DefaultCacheTTL
+}
+
+func (s *Scraping) cacheStaleTTL() time.Duration {
+	if s.CacheStaleTTL > 0 {
+		return s.CacheStaleTTL
+	}
+	return DefaultCacheStaleTTL
+}
+
+func (s *Scraping) cacheErrorTTL() time.Duration {
+	if s.CacheErrorTTL > 0 {
+		return s.CacheErrorTTL
+	}
+	return DefaultCacheErrorTTL
+}
+
+func (s *Scraping) cacheRefreshTimeout() time.Duration {
+	if s.CacheRefreshTimeout > 0 {
+		return s.CacheRefreshTimeout
+	}
+	return DefaultCacheRefreshTimeout
+}
+
+// Looks up a result by its SourceType and SourceKey instead of its url
+// Only results from SourceIdentifier scrapers are kept by source, except for SourceURL
+// where the key is the url itself
+func (s *Scraping) CachedSource(sourceType SourceType, sourceKey string) (*ScrapeInfo, bool) {
+	if s.Cache == nil {
+		return nil, false
+	}
+	entry, ok := s.Cache.Get(SourceCacheKey(sourceType, sourceKey))
+	if !ok && sourceType == SourceURL {
+		entry, ok = s.Cache.Get(s.StandardizeURL(sourceKey))
+	}
+	if !ok || entry.Info == nil {
+		return nil, false
+	}
+	return copyInfo(entry.Info), true
+}
+
+// Serves link from the cache if it can, otherwise scrapes and caches it
+func (s *Scraping) cachedScrape(ctx context.Context, link string) (*ScrapeInfo, error) {
+	key := s.cacheKey(link)
+	now := time.Now()
+
+	if entry, ok := s.Cache.Get(key); ok {
+		switch {
+		case entry.Fresh(now) && entry.Info == nil:
+			return nil, entry.error()
+		case entry.Fresh(now):
+			return entryInfo(entry, link), nil
+		case entry.Info != nil && now.Before(entry.ExpiresAt.Add(s.cacheStaleTTL())):
+			s.refreshInBackground(key, link)
+			return entryInfo(entry, link), nil
+		default:
+			s.Cache.Delete(key)
+		}
+	}
+
+	info, err := s.scrape(ctx, link)
+	s.storeInCache(key, info, err)
+	return info, err
+}
+
+// Copies the cached info so callers can't change what's in the cache
+func entryInfo(entry *CacheEntry, link string) *ScrapeInfo {
+	info := copyInfo(entry.Info)
+	info.URL = link
+	return info
+}
+
+// A copy of info that shares nothing with it
+// Meta is copied through JSON when its type is registered with RegisterMetaType,
+// otherwise the copy points at the same meta
+func copyInfo(info *ScrapeInfo) *ScrapeInfo {
+	c := *info
+	c.ThumbnailSources = append([]string(nil), info.ThumbnailSources...)
+	c.Thumbnails = append([]Thumbnail(nil), info.Thumbnails...)
+	c.FallbackErrors = append([]FallbackError(nil), info.FallbackErrors...)
+	c.Meta = copyMeta(info.Meta)
+	return &c
+}
+
+func copyMeta(meta interface{}) interface{} {
+	if meta == nil {
+		return nil
+	}
+	metaTypesMu.RLock()
+	newMeta, ok := metaTypes[metaTypeName(meta)]
+	metaTypesMu.RUnlock()
+	if !ok {
+		return meta
+	}
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return meta
+	}
+	c := newMeta()
+	if err := json.Unmarshal(b, c); err != nil {
+		return meta
+	}
+	return c
+}
+
+func (s *Scraping) storeInCache(key string, info *ScrapeInfo, err error) {
+	// Cancellations say nothing about the link itself
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	// Neither do rate limits and outages, the next try might well work
+	if IsRetryable(err) {
+		return
+	}
+
+	now := time.Now()
+	entry := &CacheEntry{
+		StoredAt: now,
+	}
+	if err != nil {
+		entry.Err = err.Error()
+		entry.err = err
+		var se *ScrapeError
+		if errors.As(err, &se) && se.Err != nil {
+			entry.Err = se.Err.Error()
+			entry.err = se.Err
+			entry.ErrKind = se.Kind
+			entry.ErrSource = se.Source
+			entry.ErrStatus = se.Status
+		}
+		entry.ExpiresAt = now.Add(s.cacheErrorTTL())
+		s.Cache.Set(key, entry)
+		return
+	}
+
+	entry.Info = copyInfo(info)
+	entry.ExpiresAt = now.Add(s.cacheTTL(info))
+	s.Cache.Set(key, entry)
+}
+
+// Rescrapes a stale link without making the caller wait on it
+// Only one refresh runs per key at a time
+func (s *Scraping) refreshInBackground(key, link string) {
+	s.mu.Lock()
+	if s.refreshing == nil {
+		s.refreshing = make(map[string]bool)
+	}
+	if s.refreshing[key] {
+		s.mu.Unlock()
+		return
+	}
+	s.refreshing[key] = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.refreshing, key)
+			s.mu.Unlock()
+		}()
+		// Nobody is waiting on this so nothing else would ever stop it
+		ctx, cancel := context.WithTimeout(context.Background(), s.cacheRefreshTimeout())
+		defer cancel()
+		info, err := s.scrape(ctx, link)
+		// A failed refresh shouldn't throw away the stale copy we still have
+		if err == nil {
+			s.storeInCache(key, info, nil)
+		}
+	}()
+}
+
+// An in memory Cache that drops the least recently used entries once it's full
+type MemoryCache struct {
+	Size int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type memoryCacheItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// A size of 0 or less never drops anything
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{
+		Size: size,
+	}
+}
+
+// Must be called with the lock held
+func (c *MemoryCache) init() {
+	if c.entries == nil {
+		c.order = list.New()
+		c.entries = make(map[string]*list.Element)
+	}
+}
+
+func (c *MemoryCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*memoryCacheItem).entry, true
+}
+
+func (c *MemoryCache) Set(key string, entry *CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*memoryCacheItem).entry = entry
+		c.order.MoveToFront(el)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(&memoryCacheItem{key, entry})
+	for c.Size > 0 && c.order.Len() > c.Size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheItem).key)
+	}
+	return nil
+}
+
+func (c *MemoryCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+// Meta is an interface{} so JSON can't know what to decode it into on its own
+// FileCache stores the name of the meta's type next to it and looks it up here
+// Custom scrapers can add their meta types with RegisterMetaType so they come back typed
+var metaTypes = map[string]func() interface{}{}
+var metaTypesMu sync.RWMutex
+
+func init() {
+	RegisterMetaType(func() interface{} { return &GenericPageMeta{} })
+	RegisterMetaType(func() interface{} { return &GenericFileMeta{} })
+	RegisterMetaType(func() interface{} { return &OembedMeta{} })
+	RegisterMetaType(func() interface{} { return &RedditPostMeta{} })
+	RegisterMetaType(func() interface{} { return &RedditCommentMeta{} })
+	RegisterMetaType(func() interface{} { return &RedditSubredditMeta{} })
+	RegisterMetaType(func() interface{} { return &RedditUserMeta{} })
+	RegisterMetaType(func() interface{} { return &TwitterTweetMeta{} })
+	RegisterMetaType(func() interface{} { return &YouTubeVideoMeta{} })
+}
+
+func metaTypeName(meta interface{}) string {
+	return reflect.TypeOf(meta).String()
+}
+
+// newMeta should return a pointer to a new, empty meta
+func RegisterMetaType(newMeta func() interface{}) {
+	metaTypesMu.Lock()
+	defer metaTypesMu.Unlock()
+	metaTypes[metaTypeName(newMeta())] = newMeta
+}
+
+// Keeps each entry as a JSON file in Dir so the cache survives restarts and can be shared
+type FileCache struct {
+	Dir string
+}
+
+func NewFileCache(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileCache{Dir: dir}, nil
+}
+
+type fileCacheEntry struct {
+	CacheEntry
+	Meta     json.RawMessage
+	MetaType string
+}
+
+func (c *FileCache) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *FileCache) Get(key string) (*CacheEntry, bool) {
+	b, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var stored fileCacheEntry
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, false
+	}
+	entry := stored.CacheEntry
+	if entry.Info != nil {
+		metaTypesMu.RLock()
+		newMeta, ok := metaTypes[stored.MetaType]
+		metaTypesMu.RUnlock()
+		if ok {
+			meta := newMeta()
+			if err := json.Unmarshal(stored.Meta, meta); err == nil {
+				entry.Info.Meta = meta
+			}
+		}
+	}
+	return &entry, true
+}
+
+func (c *FileCache) Set(key string, entry *CacheEntry) error {
+	stored := fileCacheEntry{CacheEntry: *entry}
+	if entry.Info != nil && entry.Info.Meta != nil {
+		meta, err := json.Marshal(entry.Info.Meta)
+		if err != nil {
+			return err
+		}
+		stored.Meta = meta
+		stored.MetaType = metaTypeName(entry.Info.Meta)
+		info := *entry.Info
+		info.Meta = nil
+		stored.Info = &info
+	}
+	b, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path(key), b, 0644)
+}
+
+// Writes to a temp file next to path then renames it into place, so readers in
+// this process or any other never see half a file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (c *FileCache) Delete(key string) error {
+	err := os.Remove(c.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,242 @@
+package vinscraper
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Counts how many times each link was actually scraped
+type countingScraper struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (c *countingScraper) WantsURL(link string) bool {
+	return true
+}
+
+func (c *countingScraper) Scrape(link string) (*ScrapeInfo, error) {
+	c.mu.Lock()
+	c.count++
+	c.mu.Unlock()
+	switch {
+	case strings.Contains(link, "dead"):
+		return nil, errors.New("dead link")
+	case strings.Contains(link, "gone"):
+		return nil, httpStatusError(SourceNameReddit, 404, ErrRedditNoChildren)
+	case strings.Contains(link, "busy"):
+		return nil, httpStatusError(SourceNameGeneric, 503, errors.New("try later"))
+	}
+	return &ScrapeInfo{
+		Title:            "Page",
+		SourceType:       SourceURL,
+		SourceKey:        link,
+		ThumbnailSources: []string{"https://example.com/a.png"},
+		Meta:             &GenericPageMeta{Authors: []string{"games"}},
+	}, nil
+}
+
+func (c *countingScraper) scrapes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+func TestScrapingCache(t *testing.T) {
+	scraper := &countingScraper{}
+	scraping := &Scraping{
+		Cache:    NewMemoryCache(10),
+		Scrapers: []Scraper{scraper},
+	}
+
+	for _, link := range []string{"https://example.com/a", "https://EXAMPLE.com/a#top", "https://example.com/a?utm_source=x"} {
+		info, err := scraping.Scrape(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.URL != link {
+			t.Errorf("Expected URL %s but got %s", link, info.URL)
+		}
+	}
+	if scraper.scrapes() != 1 {
+		t.Errorf("Expected 1 scrape but there were %d", scraper.scrapes())
+	}
+
+	if _, ok := scraping.CachedSource(SourceURL, "https://example.com/a"); !ok {
+		t.Error("Expected to find the result by its source")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := scraping.Scrape("https://example.com/dead"); err == nil {
+			t.Fatal("Expected an error")
+		} else if i == 1 && !errors.Is(err, ErrCachedFailure) {
+			t.Errorf("Expected the second error to be cached but got '%s'", err)
+		}
+	}
+	if scraper.scrapes() != 2 {
+		t.Errorf("Expected 2 scrapes but there were %d", scraper.scrapes())
+	}
+
+	// Cached failures keep what kind of failure they were
+	for i := 0; i < 2; i++ {
+		_, err := scraping.Scrape("https://example.com/gone")
+		var se *ScrapeError
+		if !errors.As(err, &se) || se.Kind != ErrorKindNotFound || se.Status != 404 || se.Source != SourceNameReddit {
+			t.Errorf("Expected a not found ScrapeError but got %#v", err)
+		}
+		if !errors.Is(err, ErrRedditNoChildren) {
+			t.Errorf("Expected '%s' to still be ErrRedditNoChildren", err)
+		}
+		if i == 1 && !errors.Is(err, ErrCachedFailure) {
+			t.Errorf("Expected the second error to be cached but got '%s'", err)
+		}
+	}
+	if scraper.scrapes() != 3 {
+		t.Errorf("Expected 3 scrapes but there were %d", scraper.scrapes())
+	}
+
+	// Failures that might work next time aren't cached
+	for i := 0; i < 2; i++ {
+		if _, err := scraping.Scrape("https://example.com/busy"); errors.Is(err, ErrCachedFailure) || !IsRetryable(err) {
+			t.Errorf("Expected a retryable error that wasn't cached but got '%s'", err)
+		}
+	}
+	if scraper.scrapes() != 5 {
+		t.Errorf("Expected 5 scrapes but there were %d", scraper.scrapes())
+	}
+
+	// Changing a result doesn't change what's cached
+	info, _ := scraping.Scrape("https://example.com/a")
+	info.ThumbnailSources[0] = "changed"
+	info.Meta.(*GenericPageMeta).Authors[0] = "changed"
+	info, _ = scraping.Scrape("https://example.com/a")
+	if info.ThumbnailSources[0] == "changed" || info.Meta.(*GenericPageMeta).Authors[0] == "changed" {
+		t.Errorf("Expected the cached result to be untouched but got %v %v", info.ThumbnailSources, info.Meta)
+	}
+}
+
+// Knows every link by the last part of its path, wherever it's hosted
+type sourceScraper struct {
+	countingScraper
+}
+
+func (s *sourceScraper) SourceOf(link string) (SourceType, string, bool) {
+	return "test", path.Base(link), true
+}
+
+func TestScrapingCacheSource(t *testing.T) {
+	scraper := &sourceScraper{}
+	cache := NewMemoryCache(10)
+	scraping := &Scraping{
+		Cache:    cache,
+		Scrapers: []Scraper{scraper},
+	}
+
+	for _, link := range []string{"https://example.com/games/root", "https://mirror.example.org/root"} {
+		if _, err := scraping.Scrape(link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if scraper.scrapes() != 1 {
+		t.Errorf("Expected the second link to the same source to be cached but there were %d scrapes", scraper.scrapes())
+	}
+	if cache.order.Len() != 1 {
+		t.Errorf("Expected one entry for the source but there are %d", cache.order.Len())
+	}
+	if _, ok := scraping.CachedSource("test", "root"); !ok {
+		t.Error("Expected to find the result by its source")
+	}
+}
+
+func TestScrapingCacheStale(t *testing.T) {
+	scraper := &countingScraper{}
+	scraping := &Scraping{
+		Cache:           NewMemoryCache(10),
+		CacheStaleTTL:   time.Hour,
+		DefaultCacheTTL: time.Nanosecond,
+		Scrapers:        []Scraper{scraper},
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := scraping.Scrape("https://example.com/a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second scrape was served stale and refreshed in the background
+	deadline := time.Now().Add(time.Second)
+	for scraper.scrapes() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if scraper.scrapes() != 2 {
+		t.Errorf("Expected 2 scrapes but there were %d", scraper.scrapes())
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	cache := NewMemoryCache(2)
+	cache.Set("a", &CacheEntry{})
+	cache.Set("b", &CacheEntry{})
+	cache.Get("a")
+	cache.Set("c", &CacheEntry{})
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("Expected %s to still be cached", key)
+		}
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scraper-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache, err := NewFileCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := &CacheEntry{
+		Info: &ScrapeInfo{
+			Title:      "A post",
+			SourceType: SourceRedditPost,
+			Meta: &RedditPostMeta{
+				RedditThingMeta: RedditThingMeta{SubredditPrefixed: "r/boardgames"},
+				Spoiler:         true,
+			},
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	if err := cache.Set("key", entry); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := cache.Get("key")
+	if !ok {
+		t.Fatal("Expected the entry to be cached")
+	}
+	meta, ok := got.Info.Meta.(*RedditPostMeta)
+	if !ok {
+		t.Fatalf("Expected *RedditPostMeta but got %T", got.Info.Meta)
+	}
+	if !meta.Spoiler || meta.SubredditPrefixed != "r/boardgames" || got.Info.Title != "A post" {
+		t.Errorf("Entry did not survive the round trip: %+v %+v", got.Info, meta)
+	}
+
+	cache.Delete("key")
+	if _, ok := cache.Get("key"); ok {
+		t.Error("Expected the entry to be deleted")
+	}
+}
@@ -0,0 +1,136 @@
+package vinscraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/monstercat/golib/request"
+)
+
+// What sort of failure a ScrapeError is, so callers can decide whether to retry
+// and what to tell the user
+type ErrorKind string
+
+const (
+	ErrorKindUnknown     ErrorKind = "unknown"
+	ErrorKindNotFound    ErrorKind = "not_found"
+	ErrorKindRateLimited ErrorKind = "rate_limited"
+	ErrorKindAuth        ErrorKind = "auth_failed"
+	ErrorKindBlocked     ErrorKind = "blocked"
+	ErrorKindTransient   ErrorKind = "transient"
+	ErrorKindInvalid     ErrorKind = "invalid" // The link or the scraper's config is no good
+)
+
+// Names the built in scrapers use as ScrapeError.Source, matching what NewScraping registers them as
+const (
+	SourceNameGeneric = "generic"
+	SourceNameReddit  = "reddit"
+	SourceNameTwitter = "twitter"
+	SourceNameYouTube = "youtube"
+)
+
+// Every built in scraper returns its failures as one of these
+// errors.Is still finds the sentinel errors, like ErrRedditNoChildren, that they wrap
+type ScrapeError struct {
+	Kind      ErrorKind
+	Source    string // The scraper that failed
+	Status    int    // HTTP status, 0 if the failure wasn't an HTTP response
+	Retryable bool
+	// How long the server asked us to wait before trying again, 0 if it didn't say
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ScrapeError) Error() string {
+	if e.Status != 0 {
+		return fmt.Sprintf("%s: %s (%d): %s", e.Source, e.Kind, e.Status, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Source, e.Kind, e.Err)
+}
+
+func (e *ScrapeError) Unwrap() error {
+	return e.Err
+}
+
+func newScrapeError(source string, kind ErrorKind, err error) *ScrapeError {
+	return &ScrapeError{
+		Kind:      kind,
+		Source:    source,
+		Retryable: kind == ErrorKindRateLimited || kind == ErrorKindTransient,
+		Err:       err,
+	}
+}
+
+func statusErrorKind(status int) ErrorKind {
+	switch {
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return ErrorKindNotFound
+	case status == http.StatusUnauthorized:
+		return ErrorKindAuth
+	case status == http.StatusForbidden || status == http.StatusUnavailableForLegalReasons:
+		return ErrorKindBlocked
+	case status == http.StatusTooManyRequests:
+		return ErrorKindRateLimited
+	case status == http.StatusRequestTimeout || status >= 500:
+		return ErrorKindTransient
+	}
+	return ErrorKindUnknown
+}
+
+// For when a server answered with an error status
+func httpStatusError(source string, status int, err error) *ScrapeError {
+	se := newScrapeError(source, statusErrorKind(status), err)
+	se.Status = status
+	return se
+}
+
+// Like httpStatusError but also picks up any rate limit headers on the response
+func httpResponseError(source string, resp *http.Response, err error) *ScrapeError {
+	se := httpStatusError(source, resp.StatusCode, err)
+	se.RetryAfter = retryAfter(resp.Header, time.Now())
+	return se
+}
+
+// Turns whatever a scraper failed with into a ScrapeError, guessing the kind when it can
+// Errors that are already ScrapeErrors and context cancellations are left alone
+func wrapScrapeError(source string, err error) error {
+	if err == nil {
+		return nil
+	}
+	// The ScrapeError might be buried under a *url.Error or *net.OpError from a transport
+	var se *ScrapeError
+	if errors.As(err, &se) {
+		return se
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	var reqErr *request.Error
+	if errors.As(err, &reqErr) {
+		return httpStatusError(source, reqErr.Status, err)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return newScrapeError(source, ErrorKindTransient, err)
+	}
+	return newScrapeError(source, ErrorKindUnknown, err)
+}
+
+// The Kind of err if it's a ScrapeError, ErrorKindUnknown otherwise
+func ErrorKindOf(err error) ErrorKind {
+	var se *ScrapeError
+	if errors.As(err, &se) {
+		return se.Kind
+	}
+	return ErrorKindUnknown
+}
+
+func IsRetryable(err error) bool {
+	var se *ScrapeError
+	return errors.As(err, &se) && se.Retryable
+}
@@ -0,0 +1,73 @@
+package vinscraper
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Answers every request with the same status and body
+type statusTransport struct {
+	status int
+	body   string
+}
+
+func (s *statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: s.status,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(s.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestScrapeErrorKinds(t *testing.T) {
+	link := "https://www.reddit.com/r/boardgames/comments/jn78c5/the_3_minute_board_games_top_100_games_2020/"
+	tests := []struct {
+		transport *statusTransport
+		kind      ErrorKind
+		retryable bool
+		is        error
+	}{
+		{&statusTransport{429, "slow down"}, ErrorKindRateLimited, true, nil},
+		{&statusTransport{503, ""}, ErrorKindTransient, true, nil},
+		{&statusTransport{403, ""}, ErrorKindBlocked, false, nil},
+		{&statusTransport{200, `{"data":{"children":[]}}`}, ErrorKindNotFound, false, ErrRedditNoChildren},
+	}
+
+	for i, test := range tests {
+		scraper := &RedditScraper{HTTPClient: &http.Client{Transport: test.transport}}
+		_, err := scraper.Scrape(link)
+
+		var se *ScrapeError
+		if !errors.As(err, &se) {
+			t.Errorf("[%d] Expected a *ScrapeError but got %T '%v'", i, err, err)
+			continue
+		}
+		if se.Kind != test.kind || se.Retryable != test.retryable || se.Source != SourceNameReddit {
+			t.Errorf("[%d] Unexpected error %+v", i, se)
+		}
+		if test.transport.status != 200 && se.Status != test.transport.status {
+			t.Errorf("[%d] Expected status %d but got %d", i, test.transport.status, se.Status)
+		}
+		if test.is != nil && !errors.Is(err, test.is) {
+			t.Errorf("[%d] Expected error to be '%s'", i, test.is)
+		}
+	}
+}
+
+func TestYouTubeErrorKinds(t *testing.T) {
+	quota := youTubeError(&googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}})
+	if ErrorKindOf(quota) != ErrorKindRateLimited || IsRetryable(quota) {
+		t.Errorf("Expected a quota error to be rate limited and not retryable but got %+v", quota)
+	}
+
+	missing := youTubeError(newScrapeError(SourceNameYouTube, ErrorKindNotFound, ErrVideoNotFound))
+	if ErrorKindOf(missing) != ErrorKindNotFound || !errors.Is(missing, ErrVideoNotFound) {
+		t.Errorf("Expected a not found error but got %+v", missing)
+	}
+}
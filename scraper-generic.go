@@ -1,12 +1,134 @@
 package vinscraper
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/dyatlov/go-htmlinfo/htmlinfo"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 )
 
+const (
+	DefaultMaxBodyBytes  = 5 << 20
+	DefaultScrapeTimeout = 30 * time.Second
+	DefaultHeaderTimeout = 10 * time.Second
+)
+
+var (
+	ErrContentTypeNotAllowed = errors.New("content type is not allowed")
+	ErrScrapeTimeout         = errors.New("timed out fetching link")
+	ErrBadStatus             = errors.New("link responded with an error status")
+	ErrPageParse             = errors.New("could not parse page")
+)
+
+// Types the generic scraper fetches when AllowedContentTypes isn't set
+// A trailing /* allows every subtype
+var DefaultAllowedContentTypes = []string{
+	"text/html",
+	"application/xhtml+xml",
+	"image/*",
+	"video/*",
+	"audio/*",
+	"application/pdf",
+	"text/plain",
+}
+
+// What the server sent back, shared by all the generic metas
+type GenericResponseMeta struct {
+	StatusCode int
+	FinalURL   string // Where the link ended up after redirects
+	Headers    http.Header
+}
+
+func newGenericResponseMeta(resp *http.Response) GenericResponseMeta {
+	meta := GenericResponseMeta{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header,
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		meta.FinalURL = resp.Request.URL.String()
+	}
+	return meta
+}
+
+// Meta for links that point at an HTML page
+type GenericPageMeta struct {
+	GenericResponseMeta
+
+	SiteName      string
+	Type          string // og:type, like website, article or video.movie
+	Locale        string
+	Videos        []GenericMedia
+	Audios        []GenericMedia
+	PublishedTime string   // article:published_time as the page wrote it
+	Authors       []string // article:author, usually profile urls
+
+	TwitterCard         string // summary, summary_large_image, player or app
+	TwitterCreator      string // @handle
+	TwitterPlayer       string // Url of an embeddable player for the card
+	TwitterPlayerWidth  int
+	TwitterPlayerHeight int
+
+	Schema  *SchemaObject   // From the page's JSON-LD or microdata, nil if it has none
+	Article *GenericArticle // Only with ScraperGeneric.ExtractArticle
+	Oembed  *OembedMeta     // When the page links to its oEmbed
+}
+
+// Meta for links that point at a file, like an image or a PDF, instead of a page
+type GenericFileMeta struct {
+	GenericResponseMeta
+	ContentType   string
+	ContentLength int64 // -1 if the server didn't say
+	FileName      string
+}
+
 type ScraperGeneric struct {
+	HTTPClient *http.Client // Overrides the client from Scraping
+
+	// Refuse to fetch anything that resolves to a loopback, private, link local or
+	// cloud metadata address, checked again on every redirect. Also refuses schemes
+	// other than http and https and ports other than 80 and 443
+	// Turn this on when scraping links users submit
+	SafeMode bool
+	// Ports SafeMode allows on top of 80 and 443
+	AllowedPorts []int
+
+	// Most of a page this will read. Metadata is at the top so a cut off page still parses
+	// Defaults to DefaultMaxBodyBytes
+	MaxBodyBytes int64
+	// How long the whole fetch can take, body included. Defaults to DefaultScrapeTimeout
+	Timeout time.Duration
+	// How long to wait for the response headers. Defaults to DefaultHeaderTimeout
+	HeaderTimeout time.Duration
+	// Content types to fetch, anything else fails without its body being read
+	// Defaults to DefaultAllowedContentTypes
+	AllowedContentTypes []string
+	// Find the page's main text with readability and put it in GenericPageMeta.Article
+	// Off by default since it's slow on big pages
+	ExtractArticle bool
+	// Providers to get oEmbed from without fetching the page. Defaults to DefaultOembedRegistry
+	OembedRegistry *OembedRegistry
+	// Don't use oEmbed at all, neither from the registry nor the ones pages link to
+	SkipOembed bool
+	// Don't fetch pages' web app manifests, saving a request per scrape at the cost of
+	// missing the icons and names that are only in there
+	SkipManifest bool
 }
 
 const (
@@ -18,34 +140,274 @@ func (s *ScraperGeneric) WantsURL(url string) bool {
 	return true
 }
 
+// So it always goes after the scrapers that know what they're doing
+func (s *ScraperGeneric) CatchAll() bool {
+	return true
+}
+
+func (s *ScraperGeneric) StandardizeURL(link string) string {
+	return standardizeGenericURL(link)
+}
+
 func (s *ScraperGeneric) Scrape(link string) (*ScrapeInfo, error) {
-	resp, err := http.Get(link)
+	return s.ScrapeContext(context.Background(), link)
+}
+
+func (s *ScraperGeneric) ScrapeContext(ctx context.Context, link string) (*ScrapeInfo, error) {
+	info, err := s.scrape(ctx, link)
 	if err != nil {
-		return nil, err
+		// The caller's context is still going so it was one of our own timeouts
+		if ctx.Err() == nil && isTimeout(err) {
+			err = newScrapeError(SourceNameGeneric, ErrorKindTransient, fmt.Errorf("%w: %s", ErrScrapeTimeout, err))
+		}
+		return nil, wrapScrapeError(SourceNameGeneric, err)
+	}
+	return info, nil
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, context.Canceled) ||
+		(errors.As(err, &netErr) && netErr.Timeout())
+}
+
+func (s *ScraperGeneric) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return s.Timeout
+	}
+	return DefaultScrapeTimeout
+}
+
+func (s *ScraperGeneric) headerTimeout() time.Duration {
+	if s.HeaderTimeout > 0 {
+		return s.HeaderTimeout
+	}
+	return DefaultHeaderTimeout
+}
+
+func (s *ScraperGeneric) maxBodyBytes() int64 {
+	if s.MaxBodyBytes > 0 {
+		return s.MaxBodyBytes
+	}
+	return DefaultMaxBodyBytes
+}
+
+func (s *ScraperGeneric) allowsContentType(contentType string) bool {
+	allowed := s.AllowedContentTypes
+	if allowed == nil {
+		allowed = DefaultAllowedContentTypes
+	}
+	for _, t := range allowed {
+		if t == contentType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ScraperGeneric) client(ctx context.Context) *http.Client {
+	client := baseHTTPClient(ctx, s.HTTPClient)
+	if s.SafeMode {
+		client = safeClient(client, s.AllowedPorts)
+	}
+	return limitedClient(ctx, client)
+}
+
+// Sends the request, giving up if the headers take longer than HeaderTimeout
+// The body can keep coming after that until ctx is done
+func (s *ScraperGeneric) fetch(ctx context.Context, client *http.Client, link string) (*http.Response, context.CancelFunc, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	timer := time.AfterFunc(s.headerTimeout(), cancel)
+	resp, err := client.Do(req)
+	timer.Stop()
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return resp, cancel, nil
+}
+
+func (s *ScraperGeneric) scrape(ctx context.Context, link string) (*ScrapeInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout())
+	defer cancel()
+
+	client := s.client(ctx)
+
+	// Known providers answer faster and more reliably from their endpoint than their pages do
+	// If the endpoint fails the page might still have something, so that's tried next
+	if !s.SkipOembed {
+		if item, err := s.scrapeOembed(ctx, client, link); err == nil && item != nil {
+			return item, nil
+		} else if ctx.Err() != nil {
+			return nil, err
+		}
 	}
 
+	resp, cancelFetch, err := s.fetch(ctx, client, link)
+	if err != nil {
+		return nil, err
+	}
+	defer cancelFetch()
 	defer resp.Body.Close()
 
+	// Error pages have titles too, but "Page Not Found" isn't what the link is about
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, httpResponseError(SourceNameGeneric, resp, fmt.Errorf("%w: %s", ErrBadStatus, resp.Status))
+	}
+
+	body := bufio.NewReader(resp.Body)
+	header := resp.Header.Get("Content-Type")
+	contentType, _, _ := mime.ParseMediaType(header)
+	if contentType == "" {
+		sniff, _ := body.Peek(512)
+		header = http.DetectContentType(sniff)
+		contentType, _, _ = mime.ParseMediaType(header)
+	}
+	contentType = strings.ToLower(contentType)
+
+	if !s.allowsContentType(contentType) {
+		return nil, newScrapeError(SourceNameGeneric, ErrorKindInvalid, fmt.Errorf("%w: %s", ErrContentTypeNotAllowed, contentType))
+	}
+
+	switch {
+	case contentType == "text/html" || contentType == "application/xhtml+xml":
+		// Read it all up front, htmlinfo and the schema.org parser both need it
+		page, err := ioutil.ReadAll(decodeHTML(io.LimitReader(body, s.maxBodyBytes()), header))
+		if err != nil {
+			return nil, err
+		}
+		return s.scrapeHTML(ctx, client, link, resp, page)
+	case strings.HasPrefix(contentType, "image/"):
+		item := scrapeFile(link, contentType, resp)
+		item.ThumbnailSources = []string{link}
+		item.Thumbnails = []Thumbnail{{URL: link}}
+		return item, nil
+	default:
+		return scrapeFile(link, contentType, resp), nil
+	}
+}
+
+// Files don't have much to go on besides their name, so their body is never read
+func scrapeFile(link string, contentType string, resp *http.Response) *ScrapeInfo {
+	name := ""
+	if u, err := url.Parse(link); err == nil {
+		name, _ = url.PathUnescape(path.Base(u.Path))
+		if name == "/" || name == "." {
+			name = ""
+		}
+	}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		name = params["filename"]
+	}
+
+	return &ScrapeInfo{
+		SourceType:       SourceURL,
+		SourceKey:        link,
+		Title:            name,
+		ThumbnailSources: make([]string, 0),
+		Meta: &GenericFileMeta{
+			GenericResponseMeta: newGenericResponseMeta(resp),
+			ContentType:         contentType,
+			ContentLength:       resp.ContentLength,
+			FileName:            name,
+		},
+	}
+}
+
+// Turns the page into UTF-8. The charset comes from a BOM, then the Content-Type header,
+// then a <meta charset> near the top of the page, falling back to windows-1252
+// for anything that isn't valid UTF-8
+func decodeHTML(body io.Reader, contentType string) io.Reader {
+	page := bufio.NewReaderSize(body, 1024)
+	start, _ := page.Peek(1024)
+	enc, _, _ := charset.DetermineEncoding(start, contentType)
+	// The BOM has to go too, html.Parse would take it for text and put the <head> in the <body>
+	return transform.NewReader(page, unicode.BOMOverride(enc.NewDecoder()))
+}
+
+// Pages put all sorts of line breaks and decomposed accents in their titles
+// Entities are already decoded by the parser, what's left is text the page meant to show
+func normalizeText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return norm.NFC.String(s)
+}
+
+func (s *ScraperGeneric) scrapeHTML(ctx context.Context, client *http.Client, link string, resp *http.Response, page []byte) (*ScrapeInfo, error) {
 	info := htmlinfo.NewHTMLInfo()
+	info.Client = client
+	// We do our own with ExtractArticle, htmlinfo's would only be thrown away
+	info.AllowMainContentExtraction = false
+	// Same for oEmbed, ours has timeouts and honours SkipOembed
+	info.AllowOembedFetching = false
 
 	// if url can be nil too, just then we won't be able to fetch (and generate) oembed information
-	err = info.Parse(resp.Body, &link, nil)
+	// body is UTF-8 by now, saying so stops htmlinfo from going by the page's <meta charset>
+	utf8 := "text/html; charset=utf-8"
+	err := info.Parse(bytes.NewReader(page), &link, &utf8)
+	if err != nil {
+		return nil, newScrapeError(SourceNameGeneric, ErrorKindInvalid, fmt.Errorf("%w: %s", ErrPageParse, err))
+	}
 
+	doc, err := html.Parse(bytes.NewReader(page))
 	if err != nil {
-		panic(err)
+		return nil, newScrapeError(SourceNameGeneric, ErrorKindInvalid, fmt.Errorf("%w: %s", ErrPageParse, err))
+	}
+	schema := FindSchemaObject(doc)
+
+	// Relative urls are relative to wherever the redirects ended up
+	base, _ := url.Parse(link)
+	if resp.Request != nil && resp.Request.URL != nil {
+		base = resp.Request.URL
+	}
+	tags := parsePageTags(doc, base)
+
+	var article *GenericArticle
+	if s.ExtractArticle {
+		article = extractArticle(string(page), base)
 	}
 
 	item := &ScrapeInfo{
 		SourceType:       SourceURL,
 		SourceKey:        link,
 		ThumbnailSources: make([]string, 0),
+		Meta: &GenericPageMeta{
+			GenericResponseMeta: newGenericResponseMeta(resp),
+			SiteName:            tags.SiteName,
+			Type:                tags.Type,
+			Locale:              tags.Locale,
+			Videos:              tags.Videos,
+			Audios:              tags.Audios,
+			PublishedTime:       tags.PublishedTime,
+			Authors:             tags.Authors,
+			TwitterCard:         tags.TwitterCard,
+			TwitterCreator:      tags.TwitterCreator,
+			TwitterPlayer:       tags.TwitterPlayer,
+			TwitterPlayerWidth:  tags.TwitterPlayerWidth,
+			TwitterPlayerHeight: tags.TwitterPlayerHeight,
+			Schema:              schema,
+			Article:             article,
+		},
 	}
 
 	if info.OGInfo != nil {
 		item.Title = info.OGInfo.Title
 		item.Description = info.OGInfo.Description
-		if len(info.OGInfo.Images) > 0 {
-			item.ThumbnailSources = []string{info.OGInfo.Images[0].URL}
+	}
+
+	// Structured data beats the <title> and <meta name="description">, which tend to have the site name and SEO in them
+	if schema != nil {
+		if item.Title == "" {
+			item.Title = schema.Name
+		}
+		if item.Description == "" {
+			item.Description = schema.Description
 		}
 	}
 
@@ -53,16 +415,63 @@ func (s *ScraperGeneric) Scrape(link string) (*ScrapeInfo, error) {
 		item.Title = info.Title
 	}
 
+	// Prefer whatever url the page says is its real one
+	item.StandardizedURL = standardizeGenericURL(info.CanonicalURL)
+	if item.StandardizedURL == "" && info.OGInfo != nil {
+		item.StandardizedURL = standardizeGenericURL(info.OGInfo.URL)
+	}
+	if item.StandardizedURL == "" {
+		item.StandardizedURL = standardizeGenericURL(link)
+	}
+
 	if item.Description == "" {
 		item.Description = info.Description
 	}
 
+	item.Title = normalizeText(item.Title)
+	item.Description = normalizeText(item.Description)
+	if item.Description == "" && article != nil {
+		item.Description = article.FirstParagraph()
+	}
+
 	if info.AuthorName != "" {
 		item.CreditTitle = info.AuthorName
 	}
 
-	if info.ImageSrcURL != "" {
-		item.ThumbnailSources = []string{info.ImageSrcURL}
+	if schema != nil && item.CreditTitle == "" && len(schema.Authors) > 0 {
+		item.CreditTitle = schema.Authors[0].Name
+		item.CreditURL = schema.Authors[0].URL
+	}
+
+	// Every og:image, then whatever else the page offers if it has none
+	item.Thumbnails = tags.Images
+	if len(item.Thumbnails) == 0 && tags.TwitterImage != "" {
+		item.Thumbnails = []Thumbnail{{URL: tags.TwitterImage}}
+	}
+	if src := resolveURL(base, info.ImageSrcURL); len(item.Thumbnails) == 0 && src != "" {
+		item.Thumbnails = []Thumbnail{{URL: src}}
+	}
+	if len(item.Thumbnails) == 0 && schema != nil {
+		for _, image := range schema.Images {
+			if src := resolveURL(base, image); src != "" {
+				item.Thumbnails = append(item.Thumbnails, Thumbnail{URL: src})
+			}
+		}
+	}
+	for _, thumb := range item.Thumbnails {
+		item.ThumbnailSources = append(item.ThumbnailSources, thumb.URL)
+	}
+
+	s.scrapeSiteIdentity(ctx, client, doc, base, item, tags.SiteName)
+
+	if !s.SkipOembed {
+		if endpoint := discoverOembed(doc, base); endpoint != "" {
+			// The page already gave us plenty, losing the embed isn't worth failing over
+			if o, err := fetchOembed(ctx, client, endpoint); err == nil {
+				applyOembed(item, o, false)
+				item.Meta.(*GenericPageMeta).Oembed = newOembedMeta(o, endpoint)
+			}
+		}
 	}
 
 	return item, nil
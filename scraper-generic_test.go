@@ -1,6 +1,19 @@
 package vinscraper
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/monstercat/golib/expectm"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
 
 func TestScrapeGeneric(t *testing.T) {
 	scraper := &ScraperGeneric{}
@@ -17,3 +30,212 @@ func TestScrapeGeneric(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestScrapeGenericContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	scraping := &Scraping{
+		Scrapers: []Scraper{&ScraperGeneric{}},
+	}
+	_, err := scraping.ScrapeContext(ctx, server.URL)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected '%s' but got '%v'", context.DeadlineExceeded, err)
+	}
+}
+
+func TestScrapeGenericLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cat.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("not really a png"))
+		case "/download":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", `attachment; filename="Rules of Play.pdf"`)
+		case "/linux.iso":
+			w.Header().Set("Content-Type", "application/octet-stream")
+		case "/clip.mp4":
+			w.Header().Set("Content-Type", "video/mp4")
+		case "/theme.mp3":
+			w.Header().Set("Content-Type", "audio/mpeg")
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte("Just some notes"))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/endless":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><title>Endless</title></head><body>"))
+			for r.Context().Err() == nil {
+				if _, err := w.Write([]byte("<p>more</p>")); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	// Plenty of time for the headers so only /slow ever times out, even on a busy machine
+	scraper := &ScraperGeneric{
+		HeaderTimeout: 10 * time.Second,
+		MaxBodyBytes:  64 << 10,
+	}
+	impatient := &ScraperGeneric{
+		HeaderTimeout: 50 * time.Millisecond,
+	}
+
+	info, err := scraper.Scrape(server.URL + "/cat.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectm.CheckJSON(info, &expectm.ExpectedM{
+		"Title":              "cat.png",
+		"ThumbnailSources.0": server.URL + "/cat.png",
+		"Meta.ContentType":   "image/png",
+	}); err != nil {
+		t.Error(err)
+	}
+
+	info, err = scraper.Scrape(server.URL + "/download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "Rules of Play.pdf" {
+		t.Errorf("Expected the file name from Content-Disposition but got '%s'", info.Title)
+	}
+
+	for _, file := range []string{"clip.mp4", "theme.mp3", "notes.txt"} {
+		info, err := scraper.Scrape(server.URL + "/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := info.Meta.(*GenericFileMeta); !ok || info.Title != file {
+			t.Errorf("Expected %s to be scraped as a file but got %+v", file, info)
+		}
+	}
+
+	if _, err := scraper.Scrape(server.URL + "/linux.iso"); !errors.Is(err, ErrContentTypeNotAllowed) {
+		t.Errorf("Expected '%s' but got '%v'", ErrContentTypeNotAllowed, err)
+	}
+
+	if _, err := impatient.Scrape(server.URL + "/slow"); !errors.Is(err, ErrScrapeTimeout) || !IsRetryable(err) {
+		t.Errorf("Expected a retryable '%s' but got '%v'", ErrScrapeTimeout, err)
+	}
+
+	info, err = scraper.Scrape(server.URL + "/endless")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "Endless" {
+		t.Errorf("Expected the title from the start of the page but got '%s'", info.Title)
+	}
+}
+
+func TestScrapeGenericStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("X-Served-By", "test")
+			w.Write([]byte("<html><head><title>New Home</title></head></html>"))
+		case "/broken":
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("<html><head><title>Oops</title></head></html>"))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("<html><head><title>Page Not Found</title></head></html>"))
+		}
+	}))
+	defer server.Close()
+
+	scraper := &ScraperGeneric{}
+
+	info, err := scraper.Scrape(server.URL + "/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectm.CheckJSON(info, &expectm.ExpectedM{
+		"Title":                      "New Home",
+		"Meta.StatusCode":            200,
+		"Meta.FinalURL":              server.URL + "/new",
+		"Meta.Headers.X-Served-By.0": "test",
+	}); err != nil {
+		t.Error(err)
+	}
+
+	_, err = scraper.Scrape(server.URL + "/missing")
+	if !errors.Is(err, ErrBadStatus) || ErrorKindOf(err) != ErrorKindNotFound {
+		t.Errorf("Expected a not found '%s' but got '%v'", ErrBadStatus, err)
+	}
+
+	_, err = scraper.Scrape(server.URL + "/broken")
+	var se *ScrapeError
+	if !errors.As(err, &se) || se.Status != http.StatusInternalServerError || !se.Retryable {
+		t.Errorf("Expected a retryable 500 but got '%v'", err)
+	}
+}
+
+func TestScrapeGenericCharset(t *testing.T) {
+	encode := func(enc encoding.Encoding, s string) []byte {
+		b, err := enc.NewEncoder().Bytes([]byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/header":
+			w.Header().Set("Content-Type", "text/html; charset=Shift_JIS")
+			w.Write(encode(japanese.ShiftJIS, "<html><head><title>ボードゲーム</title></head></html>"))
+		case "/meta":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write(encode(charmap.Windows1251, `<html><head><meta charset="windows-1251"><title>Настольные игры</title></head></html>`))
+		case "/bom":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write(encode(unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), "<html><head><title>Jeux de société</title></head></html>"))
+		case "/latin1":
+			w.Header().Set("Content-Type", "text/html; charset=ISO-8859-1")
+			w.Write(encode(charmap.ISO8859_1, "<html><head><title>Café</title></head></html>"))
+		case "/messy":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><head><title>\n  Café   &amp;amp;\tBoard  Games\n</title>" +
+				`<meta name="description" content="  Two &amp;lt;players&amp;gt;  only "></head></html>`))
+		}
+	}))
+	defer server.Close()
+
+	scraper := &ScraperGeneric{}
+	titles := map[string]string{
+		"/header": "ボードゲーム",
+		"/meta":   "Настольные игры",
+		"/bom":    "Jeux de société",
+		"/latin1": "Café",
+		"/messy":  "Café &amp; Board Games",
+	}
+	for path, title := range titles {
+		info, err := scraper.Scrape(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Title != title {
+			t.Errorf("Expected %s to have title '%s' but got '%s'", path, title, info.Title)
+		}
+		if path == "/messy" && info.Description != "Two &lt;players&gt; only" {
+			t.Errorf("Expected normalized description but got '%s'", info.Description)
+		}
+	}
+}
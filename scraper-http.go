@@ -0,0 +1,47 @@
+package vinscraper
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+type httpClientKey struct{}
+
+// Returns a copy of ctx carrying the client that the built in scrapers will make their requests with
+// The client is also set as the oauth2 base client so the YouTube and Twitter clients use it underneath
+func WithHTTPClient(ctx context.Context, client *http.Client) context.Context {
+	ctx = context.WithValue(ctx, httpClientKey{}, client)
+	return context.WithValue(ctx, oauth2.HTTPClient, client)
+}
+
+// Picks which client a scraper should use
+// A scraper's own client wins, then the one on the context, then http.DefaultClient
+// Whichever it is goes through Scraping's rate limiter if it has one
+func httpClient(ctx context.Context, own *http.Client) *http.Client {
+	return limitedClient(ctx, baseHTTPClient(ctx, own))
+}
+
+// httpClient without the rate limiter, for scrapers that need to change the transport first
+func baseHTTPClient(ctx context.Context, own *http.Client) *http.Client {
+	if own != nil {
+		return own
+	}
+	if client, ok := ctx.Value(httpClientKey{}).(*http.Client); ok && client != nil {
+		return client
+	}
+	return http.DefaultClient
+}
+
+func limitedClient(ctx context.Context, client *http.Client) *http.Client {
+	if limiter, ok := ctx.Value(rateLimiterKey{}).(*HostRateLimiter); ok && limiter != nil {
+		return rateLimitClient(client, limiter)
+	}
+	return client
+}
+
+// Same as httpClient but also sets the client as oauth2's base client on the returned context
+func oauth2Context(ctx context.Context, own *http.Client) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, httpClient(ctx, own))
+}
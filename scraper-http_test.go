@@ -0,0 +1,82 @@
+package vinscraper
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/monstercat/golib/expectm"
+)
+
+// Answers every request with the body for its url, 404 if there isn't one
+type fakeTransport map[string]string
+
+func (f fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, ok := f[req.URL.String()]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"text/html"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// A fakeTransport that can also redirect, like reddit does with share links
+// or a site sending a scraper somewhere it shouldn't go
+type redirectingTransport struct {
+	pages     fakeTransport
+	redirects map[string]string
+}
+
+func (f redirectingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if to, ok := f.redirects[req.URL.String()]; ok {
+		return &http.Response{
+			StatusCode: http.StatusMovedPermanently,
+			Header:     http.Header{"Location": []string{to}},
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	}
+	return f.pages.RoundTrip(req)
+}
+
+func TestScrapingHTTPClient(t *testing.T) {
+	scraping := NewScraping()
+	scraping.HTTPClient = &http.Client{
+		Transport: fakeTransport{
+			"https://example.com/page":                     `<html><head><title>Fake Page</title></head><body></body></html>`,
+			"https://api.reddit.com/api/info?id=t3_jn78c5": `{"data":{"children":[{"data":{"author":"someone","id":"jn78c5","title":"Fake Post","subreddit":"boardgames","subreddit_name_prefixed":"r/boardgames"}}]}}`,
+			"https://api.reddit.com/r/boardgames/about":    `{"data":{"community_icon":"https://styles.redditmedia.com/icon.png?width=256&amp;s=abc","primary_color":"#0079d3"}}`,
+		},
+	}
+
+	tests := map[string]*expectm.ExpectedM{
+		"https://example.com/page": {
+			"Title":      "Fake Page",
+			"SourceType": SourceURL,
+		},
+		"https://www.reddit.com/r/boardgames/comments/jn78c5/the_3_minute_board_games_top_100_games_2020/": {
+			"Title":                  "Fake Post",
+			"CreditTitle":            "someone",
+			"Meta.SubredditPrefixed": "r/boardgames",
+			"SiteName":               "r/boardgames",
+			"SiteIconURL":            "https://styles.redditmedia.com/icon.png?width=256&s=abc",
+			"ThemeColor":             "#0079d3",
+		},
+	}
+
+	for link, expected := range tests {
+		info, err := scraping.Scrape(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := expectm.CheckJSON(info, expected); err != nil {
+			t.Errorf("%s: %s", link, err)
+		}
+	}
+}
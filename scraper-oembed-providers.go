@@ -0,0 +1,154 @@
+package vinscraper
+
+// A trimmed down copy of https://oembed.com/providers.json with the providers people link to most
+// OembedRegistry.Refresh swaps it for the full, current list
+// Schemes starting with http:// match https:// too
+const bundledOembedProviders = `[
+	{
+		"provider_name": "YouTube",
+		"provider_url": "https://www.youtube.com/",
+		"endpoints": [{
+			"schemes": [
+				"http://*.youtube.com/watch*",
+				"http://*.youtube.com/v/*",
+				"http://*.youtube.com/shorts/*",
+				"http://youtube.com/watch*",
+				"http://youtube.com/shorts/*",
+				"http://youtu.be/*"
+			],
+			"url": "https://www.youtube.com/oembed"
+		}]
+	},
+	{
+		"provider_name": "Vimeo",
+		"provider_url": "https://vimeo.com/",
+		"endpoints": [{
+			"schemes": [
+				"http://vimeo.com/*",
+				"http://vimeo.com/album/*/video/*",
+				"http://vimeo.com/channels/*/*",
+				"http://vimeo.com/groups/*/videos/*",
+				"http://player.vimeo.com/video/*"
+			],
+			"url": "https://vimeo.com/api/oembed.json"
+		}]
+	},
+	{
+		"provider_name": "Spotify",
+		"provider_url": "https://spotify.com/",
+		"endpoints": [{
+			"schemes": [
+				"http://open.spotify.com/*"
+			],
+			"url": "https://open.spotify.com/oembed"
+		}]
+	},
+	{
+		"provider_name": "SoundCloud",
+		"provider_url": "https://soundcloud.com/",
+		"endpoints": [{
+			"schemes": [
+				"http://soundcloud.com/*",
+				"http://on.soundcloud.com/*",
+				"http://m.soundcloud.com/*"
+			],
+			"url": "https://soundcloud.com/oembed"
+		}]
+	},
+	{
+		"provider_name": "TikTok",
+		"provider_url": "https://www.tiktok.com/",
+		"endpoints": [{
+			"schemes": [
+				"http://www.tiktok.com/*/video/*",
+				"http://m.tiktok.com/v/*"
+			],
+			"url": "https://www.tiktok.com/oembed"
+		}]
+	},
+	{
+		"provider_name": "Dailymotion",
+		"provider_url": "https://www.dailymotion.com/",
+		"endpoints": [{
+			"schemes": [
+				"http://www.dailymotion.com/video/*",
+				"http://dai.ly/*"
+			],
+			"url": "https://www.dailymotion.com/services/oembed"
+		}]
+	},
+	{
+		"provider_name": "Flickr",
+		"provider_url": "https://www.flickr.com/",
+		"endpoints": [{
+			"schemes": [
+				"http://*.flickr.com/photos/*",
+				"http://flic.kr/p/*"
+			],
+			"url": "https://www.flickr.com/services/oembed/"
+		}]
+	},
+	{
+		"provider_name": "Twitter",
+		"provider_url": "https://twitter.com/",
+		"endpoints": [{
+			"schemes": [
+				"http://twitter.com/*/status/*",
+				"http://*.twitter.com/*/status/*"
+			],
+			"url": "https://publish.twitter.com/oembed"
+		}]
+	},
+	{
+		"provider_name": "Reddit",
+		"provider_url": "https://reddit.com/",
+		"endpoints": [{
+			"schemes": [
+				"http://reddit.com/r/*/comments/*/*",
+				"http://www.reddit.com/r/*/comments/*/*"
+			],
+			"url": "https://www.reddit.com/oembed"
+		}]
+	},
+	{
+		"provider_name": "Mixcloud",
+		"provider_url": "https://www.mixcloud.com/",
+		"endpoints": [{
+			"schemes": [
+				"http://www.mixcloud.com/*/*/"
+			],
+			"url": "https://app.mixcloud.com/oembed/"
+		}]
+	},
+	{
+		"provider_name": "CodePen",
+		"provider_url": "https://codepen.io/",
+		"endpoints": [{
+			"schemes": [
+				"http://codepen.io/*"
+			],
+			"url": "https://codepen.io/api/oembed"
+		}]
+	},
+	{
+		"provider_name": "GIPHY",
+		"provider_url": "https://giphy.com/",
+		"endpoints": [{
+			"schemes": [
+				"http://giphy.com/gifs/*",
+				"http://media.giphy.com/media/*/giphy.gif"
+			],
+			"url": "https://giphy.com/services/oembed"
+		}]
+	},
+	{
+		"provider_name": "Kickstarter",
+		"provider_url": "https://www.kickstarter.com/",
+		"endpoints": [{
+			"schemes": [
+				"http://www.kickstarter.com/projects/*"
+			],
+			"url": "https://www.kickstarter.com/services/oembed"
+		}]
+	}
+]`
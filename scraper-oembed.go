@@ -0,0 +1,208 @@
+package vinscraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/dyatlov/go-oembed/oembed"
+	"golang.org/x/net/html"
+)
+
+// Where OembedRegistry.Refresh gets the full provider list from
+const OembedProvidersURL = "https://oembed.com/providers.json"
+
+// oEmbed responses are tiny, the spec's own examples are a few hundred bytes
+const maxOembedBytes = 64 << 10
+
+// The providers ScraperGeneric uses when it isn't given its own registry
+var DefaultOembedRegistry = NewOembedRegistry()
+
+// What an oEmbed endpoint said about a link, https://oembed.com/#section2.3
+type OembedMeta struct {
+	Type         string // photo, video, link or rich
+	ProviderName string
+	ProviderURL  string
+	EndpointURL  string
+	// For video and rich. Only copied to EmbedHTML for providers in the registry, an endpoint
+	// a page links to could send anything, so sanitize it before embedding it
+	HTML     string
+	Width    int
+	Height   int
+	CacheAge int // Seconds, if the provider said
+}
+
+// The oEmbed providers we know the endpoints of, so their links can skip fetching the page
+type OembedRegistry struct {
+	mu        sync.RWMutex
+	providers *oembed.Oembed
+}
+
+// Starts out with the bundled providers
+func NewOembedRegistry() *OembedRegistry {
+	r := &OembedRegistry{}
+	if err := r.Load(strings.NewReader(bundledOembedProviders)); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Replaces the providers with a list in the format of OembedProvidersURL
+func (r *OembedRegistry) Load(providers io.Reader) error {
+	o := oembed.NewOembed()
+	if err := o.ParseProviders(providers); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.providers = o
+	r.mu.Unlock()
+	return nil
+}
+
+// Fetches the latest list from OembedProvidersURL. The registry keeps the list it has if this fails
+func (r *OembedRegistry) Refresh(ctx context.Context, client *http.Client) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, OembedProvidersURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient(ctx, client).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return httpResponseError(SourceNameGeneric, resp, ErrBadStatus)
+	}
+	return r.Load(resp.Body)
+}
+
+// The endpoint url to ask about link, ok is false if no provider has it
+func (r *OembedRegistry) Endpoint(link string) (endpoint string, providerName string, ok bool) {
+	r.mu.RLock()
+	item := r.providers.FindItem(link)
+	r.mu.RUnlock()
+	if item == nil {
+		return "", "", false
+	}
+	return item.ComposeURL(link), item.ProviderName, true
+}
+
+// Finds the <link rel="alternate" type="application/json+oembed"> a page points at its oEmbed with
+func discoverOembed(doc *html.Node, base *url.URL) string {
+	endpoint := ""
+	walkHTML(doc, func(n *html.Node) bool {
+		if endpoint != "" {
+			return false
+		}
+		if n.Data == "link" &&
+			strings.EqualFold(htmlAttr(n, "type"), "application/json+oembed") &&
+			strings.Contains(strings.ToLower(htmlAttr(n, "rel")), "alternate") {
+			endpoint = resolveURL(base, htmlAttr(n, "href"))
+		}
+		return true
+	})
+	return endpoint
+}
+
+func fetchOembed(ctx context.Context, client *http.Client, endpoint string) (*oembed.Info, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, httpResponseError(SourceNameGeneric, resp, fmt.Errorf("%w: %s", ErrBadStatus, resp.Status))
+	}
+
+	// Providers are loose with their types, go-oembed copes with the nulls and falses they send
+	info := oembed.NewInfo()
+	if err := info.FillFromJSON(io.LimitReader(resp.Body, maxOembedBytes)); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func newOembedMeta(info *oembed.Info, endpoint string) *OembedMeta {
+	return &OembedMeta{
+		Type:         info.Type,
+		ProviderName: info.ProviderName,
+		ProviderURL:  info.ProviderURL,
+		EndpointURL:  endpoint,
+		HTML:         info.HTML,
+		Width:        int(info.Width),
+		Height:       int(info.Height),
+		CacheAge:     int(info.CacheAge),
+	}
+}
+
+// Copies what oEmbed knows onto item. The author and embed are always taken since pages
+// rarely have them, the rest only fills in what's missing
+// The embed is only taken from trusted endpoints, the ones in the registry
+func applyOembed(item *ScrapeInfo, info *oembed.Info, trusted bool) {
+	if item.Title == "" {
+		item.Title = normalizeText(info.Title)
+	}
+	if item.Description == "" {
+		item.Description = normalizeText(info.Description)
+	}
+	if info.AuthorName != "" {
+		item.CreditTitle = normalizeText(info.AuthorName)
+		item.CreditURL = info.AuthorURL
+	}
+	if item.SiteName == "" {
+		item.SiteName = info.ProviderName
+	}
+	if trusted {
+		item.EmbedHTML = info.HTML
+	}
+
+	if len(item.Thumbnails) == 0 {
+		thumb := Thumbnail{URL: info.ThumbnailURL, Width: int(info.ThumbnailWidth), Height: int(info.ThumbnailHeight)}
+		// For photos the url is the photo itself
+		if info.Type == "photo" && info.URL != "" {
+			thumb = Thumbnail{URL: info.URL, Width: int(info.Width), Height: int(info.Height)}
+		}
+		if thumb.URL != "" {
+			item.Thumbnails = []Thumbnail{thumb}
+			item.ThumbnailSources = []string{thumb.URL}
+		}
+	}
+}
+
+// Scrapes a link whose provider is in the registry straight from its oEmbed endpoint
+func (s *ScraperGeneric) scrapeOembed(ctx context.Context, client *http.Client, link string) (*ScrapeInfo, error) {
+	registry := s.OembedRegistry
+	if registry == nil {
+		registry = DefaultOembedRegistry
+	}
+	endpoint, providerName, ok := registry.Endpoint(link)
+	if !ok {
+		return nil, nil
+	}
+
+	info, err := fetchOembed(ctx, client, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if info.ProviderName == "" {
+		info.ProviderName = providerName
+	}
+
+	item := &ScrapeInfo{
+		SourceType:       SourceURL,
+		SourceKey:        link,
+		ThumbnailSources: make([]string, 0),
+		StandardizedURL:  standardizeGenericURL(link),
+		Meta:             newOembedMeta(info, endpoint),
+	}
+	applyOembed(item, info, true)
+	return item, nil
+}
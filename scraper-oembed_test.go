@@ -0,0 +1,123 @@
+package vinscraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/monstercat/golib/expectm"
+)
+
+func TestOembedRegistry(t *testing.T) {
+	links := map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ":  "https://www.youtube.com/oembed",
+		"https://youtu.be/dQw4w9WgXcQ":                 "https://www.youtube.com/oembed",
+		"http://m.youtube.com/watch?v=dQw4w9WgXcQ":     "https://www.youtube.com/oembed",
+		"https://vimeo.com/76979871":                   "https://vimeo.com/api/oembed.json",
+		"https://open.spotify.com/track/4uLU6hMCjMI75": "https://open.spotify.com/oembed",
+		"https://soundcloud.com/artist/track":          "https://soundcloud.com/oembed",
+		"https://www.tiktok.com/@someone/video/123":    "https://www.tiktok.com/oembed",
+		"https://example.com/watch?v=dQw4w9WgXcQ":      "",
+	}
+	for link, expected := range links {
+		endpoint, _, ok := DefaultOembedRegistry.Endpoint(link)
+		if ok != (expected != "") || !strings.HasPrefix(endpoint, expected) {
+			t.Errorf("Expected %s to use '%s' but got '%s'", link, expected, endpoint)
+		}
+	}
+}
+
+func TestScrapeGenericOembed(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oembed":
+			if r.URL.Query().Get("url") != server.URL+"/videos/1" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{"type": "video", "version": "1.0", "title": "Unboxing  Gloomhaven", "author_name": "Rahdo",
+				"author_url": "https://example.com/rahdo", "provider_name": "Tube", "width": 640, "height": 360,
+				"thumbnail_url": "https://example.com/thumb.jpg", "thumbnail_width": 480, "thumbnail_height": 360,
+				"html": "<iframe src=\"https://example.com/embed/1\"></iframe>"}`))
+		case "/discovered.json":
+			w.Write([]byte(`{"type": "rich", "author_name": "Someone", "html": "<blockquote>Post</blockquote>"}`))
+		case "/post":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>A Post</title>
+<link rel="alternate" type="application/json+oembed" href="/discovered.json">
+</head></html>`))
+		case "/videos/gone":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>From The Page</title></head></html>`))
+		default:
+			t.Errorf("Didn't expect a request for %s", r.URL)
+		}
+	}))
+	defer server.Close()
+
+	registry := NewOembedRegistry()
+	err := registry.Load(strings.NewReader(`[{"provider_name": "Test", "provider_url": "` + server.URL + `", "endpoints": [{
+		"schemes": ["` + server.URL + `/videos/*", "` + server.URL + `/videos/gone"],
+		"url": "` + server.URL + `/oembed"
+	}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scraper := &ScraperGeneric{OembedRegistry: registry}
+
+	// Straight from the endpoint, the page is never fetched
+	info, err := scraper.Scrape(server.URL + "/videos/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectm.CheckJSON(info, &expectm.ExpectedM{
+		"Title":              "Unboxing Gloomhaven",
+		"CreditTitle":        "Rahdo",
+		"CreditURL":          "https://example.com/rahdo",
+		"SiteName":           "Tube",
+		"ThumbnailSources.0": "https://example.com/thumb.jpg",
+		"Thumbnails.0.Width": 480,
+		"EmbedHTML":          `<iframe src="https://example.com/embed/1"></iframe>`,
+		"Meta.Type":          "video",
+		"Meta.Width":         640,
+		"Meta.Height":        360,
+	}); err != nil {
+		t.Error(err)
+	}
+
+	// The endpoint doesn't know it so the page gets scraped instead
+	info, err = scraper.Scrape(server.URL + "/videos/gone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "From The Page" {
+		t.Errorf("Expected to fall back to the page but got '%s'", info.Title)
+	}
+
+	info, err = scraper.Scrape(server.URL + "/post")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectm.CheckJSON(info, &expectm.ExpectedM{
+		"Title":            "A Post",
+		"CreditTitle":      "Someone",
+		"Meta.Oembed.Type": "rich",
+		"Meta.Oembed.HTML": "<blockquote>Post</blockquote>",
+	}); err != nil {
+		t.Error(err)
+	}
+	// Whatever endpoint a page links to isn't trusted with the embed
+	if info.EmbedHTML != "" {
+		t.Errorf("Expected no EmbedHTML from a discovered endpoint but got '%s'", info.EmbedHTML)
+	}
+
+	info, err = (&ScraperGeneric{OembedRegistry: registry, SkipOembed: true}).Scrape(server.URL + "/post")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.EmbedHTML != "" || info.CreditTitle != "" {
+		t.Errorf("Expected no oEmbed with SkipOembed but got '%s' by '%s'", info.EmbedHTML, info.CreditTitle)
+	}
+}
@@ -0,0 +1,155 @@
+package vinscraper
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// An og:video or og:audio
+type GenericMedia struct {
+	URL       string
+	SecureURL string
+	Type      string
+	Width     int
+	Height    int
+}
+
+// The OpenGraph and Twitter Card tags we keep, https://ogp.me and
+// https://developer.twitter.com/en/docs/twitter-for-websites/cards/overview/markup
+type pageTags struct {
+	SiteName      string
+	Type          string
+	Locale        string
+	Images        []Thumbnail
+	Videos        []GenericMedia
+	Audios        []GenericMedia
+	PublishedTime string
+	Authors       []string
+
+	TwitterCard         string
+	TwitterCreator      string
+	TwitterImage        string
+	TwitterPlayer       string
+	TwitterPlayerWidth  int
+	TwitterPlayerHeight int
+
+	base *url.URL
+}
+
+// Goes through the <meta> tags in order since og:image:width and friends
+// belong to whichever og:image came before them
+// Image, video and audio urls are resolved against base like the page's icons are
+func parsePageTags(doc *html.Node, base *url.URL) *pageTags {
+	tags := &pageTags{base: base}
+	walkHTML(doc, func(n *html.Node) bool {
+		if n.Data != "meta" {
+			return true
+		}
+		// OpenGraph says property and Twitter says name, but pages mix them up all the time
+		key := strings.ToLower(strings.TrimSpace(htmlAttr(n, "property")))
+		if key == "" {
+			key = strings.ToLower(strings.TrimSpace(htmlAttr(n, "name")))
+		}
+		value := strings.TrimSpace(htmlAttr(n, "content"))
+		if key != "" && value != "" {
+			tags.add(key, value)
+		}
+		return false
+	})
+
+	// Images whose url was no good are kept until now so their width and height don't land on another
+	images := tags.Images[:0]
+	for _, img := range tags.Images {
+		if img.URL != "" {
+			images = append(images, img)
+		}
+	}
+	tags.Images = images
+	return tags
+}
+
+func (t *pageTags) add(key, value string) {
+	switch key {
+	case "og:site_name":
+		t.SiteName = normalizeText(value)
+	case "og:type":
+		t.Type = value
+	case "og:locale":
+		t.Locale = value
+	case "article:published_time":
+		t.PublishedTime = value
+	case "article:author":
+		t.Authors = append(t.Authors, value)
+
+	case "og:image", "og:image:url":
+		t.Images = append(t.Images, Thumbnail{URL: resolveURL(t.base, value)})
+	case "og:image:secure_url":
+		// Same image, but https
+		if img := t.lastImage(); img != nil {
+			if secure := resolveURL(t.base, value); secure != "" {
+				img.URL = secure
+			}
+		}
+	case "og:image:width":
+		if img := t.lastImage(); img != nil {
+			img.Width, _ = strconv.Atoi(value)
+		}
+	case "og:image:height":
+		if img := t.lastImage(); img != nil {
+			img.Height, _ = strconv.Atoi(value)
+		}
+	case "og:image:alt":
+		if img := t.lastImage(); img != nil {
+			img.Alt = normalizeText(value)
+		}
+
+	case "og:video", "og:video:url":
+		t.Videos = append(t.Videos, GenericMedia{URL: resolveURL(t.base, value)})
+	case "og:audio", "og:audio:url":
+		t.Audios = append(t.Audios, GenericMedia{URL: resolveURL(t.base, value)})
+	case "og:video:secure_url", "og:video:type", "og:video:width", "og:video:height":
+		if len(t.Videos) > 0 {
+			t.Videos[len(t.Videos)-1].set(strings.TrimPrefix(key, "og:video:"), value, t.base)
+		}
+	case "og:audio:secure_url", "og:audio:type":
+		if len(t.Audios) > 0 {
+			t.Audios[len(t.Audios)-1].set(strings.TrimPrefix(key, "og:audio:"), value, t.base)
+		}
+
+	case "twitter:card":
+		t.TwitterCard = value
+	case "twitter:creator":
+		t.TwitterCreator = value
+	case "twitter:image", "twitter:image:src":
+		t.TwitterImage = resolveURL(t.base, value)
+	case "twitter:player":
+		t.TwitterPlayer = resolveURL(t.base, value)
+	case "twitter:player:width":
+		t.TwitterPlayerWidth, _ = strconv.Atoi(value)
+	case "twitter:player:height":
+		t.TwitterPlayerHeight, _ = strconv.Atoi(value)
+	}
+}
+
+func (t *pageTags) lastImage() *Thumbnail {
+	if len(t.Images) == 0 {
+		return nil
+	}
+	return &t.Images[len(t.Images)-1]
+}
+
+func (m *GenericMedia) set(prop, value string, base *url.URL) {
+	switch prop {
+	case "secure_url":
+		m.SecureURL = resolveURL(base, value)
+	case "type":
+		m.Type = value
+	case "width":
+		m.Width, _ = strconv.Atoi(value)
+	case "height":
+		m.Height, _ = strconv.Atoi(value)
+	}
+}
@@ -0,0 +1,108 @@
+package vinscraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/monstercat/golib/expectm"
+)
+
+func TestScrapeGenericOpenGraph(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+<meta property="og:title" content="Root Review">
+<meta property="og:site_name" content="Meeple Mountain">
+<meta property="og:type" content="article">
+<meta property="og:locale" content="en_US">
+<meta property="og:image" content="http://example.com/box.jpg">
+<meta property="og:image:secure_url" content="https://example.com/box.jpg">
+<meta property="og:image:width" content="1200">
+<meta property="og:image:height" content="630">
+<meta property="og:image:alt" content="The box">
+<meta property="og:image" content="https://example.com/board.jpg">
+<meta property="og:video" content="https://example.com/review.mp4">
+<meta property="og:video:type" content="video/mp4">
+<meta property="og:video:width" content="1280">
+<meta property="og:audio" content="https://example.com/podcast.mp3">
+<meta property="article:published_time" content="2020-11-09T12:00:00Z">
+<meta property="article:author" content="https://example.com/authors/sam">
+<meta name="twitter:card" content="player">
+<meta name="twitter:creator" content="@meeplemountain">
+<meta name="twitter:player" content="https://example.com/embed/review">
+<meta name="twitter:player:width" content="640">
+<link rel="image_src" href="https://example.com/other.jpg">
+</head></html>`))
+	}))
+	defer server.Close()
+
+	info, err := (&ScraperGeneric{}).Scrape(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectm.CheckJSON(info, &expectm.ExpectedM{
+		"Title":                   "Root Review",
+		"ThumbnailSources.0":      "https://example.com/box.jpg",
+		"ThumbnailSources.1":      "https://example.com/board.jpg",
+		"Thumbnails.0.Width":      1200,
+		"Thumbnails.0.Height":     630,
+		"Thumbnails.0.Alt":        "The box",
+		"Thumbnails.1.URL":        "https://example.com/board.jpg",
+		"Meta.SiteName":           "Meeple Mountain",
+		"Meta.Type":               "article",
+		"Meta.Locale":             "en_US",
+		"Meta.Videos.0.URL":       "https://example.com/review.mp4",
+		"Meta.Videos.0.Type":      "video/mp4",
+		"Meta.Videos.0.Width":     1280,
+		"Meta.Audios.0.URL":       "https://example.com/podcast.mp3",
+		"Meta.PublishedTime":      "2020-11-09T12:00:00Z",
+		"Meta.Authors.0":          "https://example.com/authors/sam",
+		"Meta.TwitterCard":        "player",
+		"Meta.TwitterCreator":     "@meeplemountain",
+		"Meta.TwitterPlayer":      "https://example.com/embed/review",
+		"Meta.TwitterPlayerWidth": 640,
+	}); err != nil {
+		t.Error(err)
+	}
+	if len(info.Thumbnails) != 2 {
+		t.Errorf("Expected only the 2 og:images but got %d thumbnails", len(info.Thumbnails))
+	}
+}
+
+func TestScrapeGenericOpenGraphRelative(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/reviews/root":
+			w.Write([]byte(`<html><head>
+<meta property="og:image" content="images/box.jpg">
+<meta property="og:image" content="//cdn.example.com/board.jpg">
+<meta property="og:video" content="/videos/review.mp4">
+</head></html>`))
+		case "/twitter":
+			w.Write([]byte(`<html><head><meta name="twitter:image" content="/card.png"></head></html>`))
+		}
+	}))
+	defer server.Close()
+
+	info, err := (&ScraperGeneric{}).Scrape(server.URL + "/reviews/root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectm.CheckJSON(info, &expectm.ExpectedM{
+		"ThumbnailSources.0": server.URL + "/reviews/images/box.jpg",
+		"ThumbnailSources.1": "http://cdn.example.com/board.jpg",
+		"Meta.Videos.0.URL":  server.URL + "/videos/review.mp4",
+	}); err != nil {
+		t.Error(err)
+	}
+
+	info, err = (&ScraperGeneric{}).Scrape(server.URL + "/twitter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.ThumbnailSources) != 1 || info.ThumbnailSources[0] != server.URL+"/card.png" {
+		t.Errorf("Expected the twitter:image resolved against the page but got %v", info.ThumbnailSources)
+	}
+}
@@ -0,0 +1,205 @@
+package vinscraper
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rates NewHostRateLimiter starts with, in requests per second
+// Reddit asks for about one a second from clients that aren't using OAuth
+// and allows 100 a minute through OAuth, which goes to oauth.reddit.com so it gets its own bucket
+var DefaultHostRates = map[string]float64{
+	"reddit.com":       1,
+	"oauth.reddit.com": 100.0 / 60,
+}
+
+// A token bucket rate limiter per hostname
+// Requests wait their turn in the order they arrive, so concurrent callers are served fairly
+type HostRateLimiter struct {
+	Rate  float64 // Requests per second for hosts without an override, 0 is unlimited
+	Burst int     // How many requests can go at once after a quiet period
+	// Called every time a request had to wait, so throttling can be logged or measured
+	OnWait func(host string, wait time.Duration)
+
+	mu        sync.Mutex
+	overrides map[string]hostRate
+	buckets   map[string]*tokenBucket
+	stats     map[string]*HostRateStats
+}
+
+type hostRate struct {
+	rate  float64
+	burst int
+}
+
+type HostRateStats struct {
+	Requests  int
+	Waited    int // How many requests had to wait
+	TotalWait time.Duration
+	MaxWait   time.Duration
+}
+
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// Takes a token, going into debt if there isn't one
+// The debt is how long the caller has to wait, and everyone after them waits longer
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func NewHostRateLimiter(rate float64, burst int) *HostRateLimiter {
+	l := &HostRateLimiter{
+		Rate:  rate,
+		Burst: burst,
+	}
+	for host, r := range DefaultHostRates {
+		l.SetHostRate(host, r, 1)
+	}
+	return l
+}
+
+// Overrides the rate for host and all of its subdomains, which share one bucket
+func (l *HostRateLimiter) SetHostRate(host string, rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.overrides == nil {
+		l.overrides = make(map[string]hostRate)
+	}
+	host = strings.ToLower(host)
+	l.overrides[host] = hostRate{rate, burst}
+	delete(l.buckets, host)
+}
+
+// Finds the bucket key and rate for host, must be called with the lock held
+func (l *HostRateLimiter) hostRate(host string) (string, hostRate) {
+	for h := host; h != ""; {
+		if r, ok := l.overrides[h]; ok {
+			return h, r
+		}
+		i := strings.Index(h, ".")
+		if i < 0 {
+			break
+		}
+		h = h[i+1:]
+	}
+	return host, hostRate{l.Rate, l.Burst}
+}
+
+// Blocks until a request to host is allowed and returns how long that took
+func (l *HostRateLimiter) Wait(ctx context.Context, host string) (time.Duration, error) {
+	host = strings.ToLower(host)
+
+	l.mu.Lock()
+	key, rate := l.hostRate(host)
+	if rate.rate <= 0 {
+		l.mu.Unlock()
+		return 0, nil
+	}
+	if l.buckets == nil {
+		l.buckets = make(map[string]*tokenBucket)
+		l.stats = make(map[string]*HostRateStats)
+	}
+	bucket, ok := l.buckets[key]
+	if !ok {
+		burst := float64(rate.burst)
+		if burst < 1 {
+			burst = 1
+		}
+		bucket = &tokenBucket{rate: rate.rate, burst: burst, tokens: burst, last: time.Now()}
+		l.buckets[key] = bucket
+	}
+	wait := bucket.reserve(time.Now())
+	stats, ok := l.stats[key]
+	if !ok {
+		stats = &HostRateStats{}
+		l.stats[key] = stats
+	}
+	stats.Requests++
+	if wait > 0 {
+		stats.Waited++
+		stats.TotalWait += wait
+		if wait > stats.MaxWait {
+			stats.MaxWait = wait
+		}
+	}
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return 0, nil
+	}
+	if l.OnWait != nil {
+		l.OnWait(key, wait)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return wait, nil
+	case <-ctx.Done():
+		// Give the token back so the next request to come along can have it
+		// Ones already waiting keep the wait they were given when they reserved
+		l.mu.Lock()
+		bucket.tokens++
+		l.mu.Unlock()
+		return 0, ctx.Err()
+	}
+}
+
+// What the limiter has done for host so far. Hosts with an override are reported
+// under the override, EG: api.reddit.com is counted as reddit.com
+func (l *HostRateLimiter) Stats(host string) HostRateStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key, _ := l.hostRate(strings.ToLower(host))
+	if stats, ok := l.stats[key]; ok {
+		return *stats
+	}
+	return HostRateStats{}
+}
+
+type rateLimitedTransport struct {
+	limiter *HostRateLimiter
+	base    http.RoundTripper
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, err := t.limiter.Wait(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+type rateLimiterKey struct{}
+
+func withRateLimiter(ctx context.Context, limiter *HostRateLimiter) context.Context {
+	return context.WithValue(ctx, rateLimiterKey{}, limiter)
+}
+
+// Returns a copy of client whose requests, redirects included, wait on the limiter
+func rateLimitClient(client *http.Client, limiter *HostRateLimiter) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	limited := *client
+	limited.Transport = &rateLimitedTransport{limiter, base}
+	return &limited
+}
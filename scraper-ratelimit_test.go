@@ -0,0 +1,81 @@
+package vinscraper
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHostRateLimiter(t *testing.T) {
+	limiter := NewHostRateLimiter(20, 1)
+	waits := 0
+	limiter.OnWait = func(host string, wait time.Duration) {
+		waits++
+	}
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := limiter.Wait(context.Background(), "example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Other hosts have their own buckets
+	if wait, _ := limiter.Wait(context.Background(), "other.com"); wait != 0 {
+		t.Errorf("Expected other.com not to wait but it waited %s", wait)
+	}
+
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("Expected 3 waits of 50ms but only took %s", elapsed)
+	}
+	stats := limiter.Stats("example.com")
+	if stats.Requests != 4 || stats.Waited != 3 || waits != 3 {
+		t.Errorf("Unexpected stats %+v with %d waits reported", stats, waits)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := limiter.Wait(ctx, "example.com"); err != context.Canceled {
+		t.Errorf("Expected '%s' but got '%v'", context.Canceled, err)
+	}
+}
+
+func TestHostRateLimiterDefaults(t *testing.T) {
+	limiter := NewHostRateLimiter(0, 0)
+	for _, host := range []string{"api.reddit.com", "oauth.reddit.com"} {
+		if wait, _ := limiter.Wait(context.Background(), host); wait != 0 {
+			t.Errorf("Expected the first request to %s not to wait but it waited %s", host, wait)
+		}
+	}
+	// OAuth isn't held back by the logged out rate
+	if stats := limiter.Stats("oauth.reddit.com"); stats.Requests != 1 {
+		t.Errorf("Expected oauth.reddit.com to have its own bucket but got %+v", stats)
+	}
+	if stats := limiter.Stats("www.reddit.com"); stats.Requests != 1 {
+		t.Errorf("Expected the rest of reddit to share a bucket but got %+v", stats)
+	}
+}
+
+func TestScrapingRateLimiter(t *testing.T) {
+	limiter := NewHostRateLimiter(0, 0)
+	limiter.SetHostRate("reddit.com", 100, 1)
+
+	scraping := NewScraping()
+	scraping.RateLimiter = limiter
+	scraping.HTTPClient = &http.Client{
+		Transport: fakeTransport{
+			"https://api.reddit.com/api/info?id=t3_jn78c5": `{"data":{"children":[{"data":{"id":"jn78c5","title":"Fake Post"}}]}}`,
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := scraping.Scrape("https://www.reddit.com/r/boardgames/comments/jn78c5/the_3_minute_board_games_top_100_games_2020/"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// api.reddit.com shares the reddit.com bucket
+	if stats := limiter.Stats("api.reddit.com"); stats.Requests != 3 || stats.Waited != 2 {
+		t.Errorf("Expected every reddit request to go through the limiter but got %+v", stats)
+	}
+}
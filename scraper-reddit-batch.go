@@ -0,0 +1,149 @@
+package vinscraper
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	// The most fullnames reddit takes in one /api/info call
+	RedditInfoBatchSize = 100
+	// A reasonable RedditScraper.BatchWindow, long enough for the reddit links in a ScrapeBatch
+	// to pile up without anyone noticing the wait
+	DefaultRedditBatchWindow = 50 * time.Millisecond
+	// How long a batched request gets, since no single caller's context can cancel it
+	redditBatchTimeout = 30 * time.Second
+)
+
+// Posts and comments share /api/info calls when there's a BatchWindow, so ScrapeBatch and
+// ScrapeStream let a call's worth of them run at once instead of holding them to HostConcurrency
+func (rs *RedditScraper) BatchSize(link string) int {
+	if rs.BatchWindow <= 0 {
+		return 0
+	}
+	l, ok := parseRedditLink(link)
+	if !ok || (l.kind != redditLinkPost && l.kind != redditLinkComment) {
+		return 0
+	}
+	return RedditInfoBatchSize
+}
+
+// /api/info with posts and comments mixed together
+type redditInfoListing struct {
+	Data struct {
+		Children []struct {
+			Kind string          `json:"kind"`
+			Data json.RawMessage `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+type redditBatchResult struct {
+	data json.RawMessage
+	err  error
+}
+
+// The fullnames waiting for the next /api/info call and who's waiting on each of them
+type redditBatch struct {
+	ctx     context.Context
+	waiters map[string][]chan redditBatchResult
+	order   []string
+	timer   *time.Timer
+}
+
+// Carries the values of the context it's made from, the HTTP client and rate limiter
+// mostly, but is never cancelled by it
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// Gets the thing with fullname, like t3_jn78c5, from /api/info along with whatever
+// else is asked for within BatchWindow of it
+func (rs *RedditScraper) batchInfo(ctx context.Context, fullname string) (json.RawMessage, error) {
+	result := make(chan redditBatchResult, 1)
+
+	rs.batchMu.Lock()
+	batch := rs.batch
+	if batch == nil {
+		batch = &redditBatch{
+			ctx:     detachedContext{ctx},
+			waiters: make(map[string][]chan redditBatchResult),
+		}
+		batch.timer = time.AfterFunc(rs.BatchWindow, func() { rs.flushBatch(batch) })
+		rs.batch = batch
+	}
+	if _, ok := batch.waiters[fullname]; !ok {
+		batch.order = append(batch.order, fullname)
+	}
+	batch.waiters[fullname] = append(batch.waiters[fullname], result)
+	// Reddit won't take any more, the next lookup starts a new batch
+	full := len(batch.order) >= RedditInfoBatchSize
+	if full {
+		rs.batch = nil
+	}
+	rs.batchMu.Unlock()
+
+	// No point waiting out the window either
+	if full && batch.timer.Stop() {
+		go rs.flushBatch(batch)
+	}
+
+	select {
+	case r := <-result:
+		return r.data, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (rs *RedditScraper) flushBatch(batch *redditBatch) {
+	// Once it's off the scraper nobody can join the batch, so it's ours to read
+	rs.batchMu.Lock()
+	if rs.batch == batch {
+		rs.batch = nil
+	}
+	rs.batchMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(batch.ctx, redditBatchTimeout)
+	defer cancel()
+
+	var body redditInfoListing
+	err := rs.RedditRequestContext(ctx, "https://api.reddit.com/api/info?id="+strings.Join(batch.order, ","), &body)
+
+	found := make(map[string]json.RawMessage)
+	for _, child := range body.Data.Children {
+		// Things carry their own fullname as name
+		var thing struct {
+			Id   string `json:"id"`
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(child.Data, &thing) != nil {
+			continue
+		}
+		if thing.Name == "" {
+			thing.Name = child.Kind + "_" + thing.Id
+		}
+		found[thing.Name] = child.Data
+	}
+
+	for _, fullname := range batch.order {
+		r := redditBatchResult{err: err}
+		if err == nil {
+			data, ok := found[fullname]
+			if ok {
+				r.data = data
+			} else {
+				r.err = newScrapeError(SourceNameReddit, ErrorKindNotFound, ErrRedditNoChildren)
+			}
+		}
+		for _, waiter := range batch.waiters[fullname] {
+			waiter <- r
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package vinscraper
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Answers /api/info with a child for every fullname asked for except t3_gone and keeps
+// track of the calls
+type redditInfoTransport struct {
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (f *redditInfoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status, body := http.StatusNotFound, `{}`
+	if req.URL.Path == "/api/info" {
+		ids := strings.Split(req.URL.Query().Get("id"), ",")
+		f.mu.Lock()
+		f.calls = append(f.calls, ids)
+		f.mu.Unlock()
+
+		children := make([]string, 0, len(ids))
+		for _, id := range ids {
+			if id == "t3_gone" {
+				continue
+			}
+			kind, short := id[:2], id[3:]
+			children = append(children, `{"kind":"`+kind+`","data":{"id":"`+short+`","name":"`+id+`","author":"a_`+short+`","title":"Title `+short+`"}}`)
+		}
+		status, body = http.StatusOK, `{"data":{"children":[`+strings.Join(children, ",")+`]}}`
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestRedditBatch(t *testing.T) {
+	transport := &redditInfoTransport{}
+	rs := &RedditScraper{
+		HTTPClient:  &http.Client{Transport: transport},
+		BatchWindow: DefaultRedditBatchWindow,
+	}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make([]*ScrapeInfo, 5)
+	errs := make([]error, 5)
+	for i, id := range []string{"post1", "post2", "post1", "gone"} {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			results[i], errs[i] = rs.ScrapePostContext(ctx, id)
+		}(i, id)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[4], errs[4] = rs.ScrapeCommentContext(ctx, "comment1")
+	}()
+	wg.Wait()
+
+	if len(transport.calls) != 1 || len(transport.calls[0]) != 4 {
+		t.Fatalf("Expected 1 call for the 4 different fullnames but got %v", transport.calls)
+	}
+	for i, title := range []string{"Title post1", "Title post2", "Title post1"} {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if results[i].Title != title {
+			t.Errorf("Expected '%s' but got '%s'", title, results[i].Title)
+		}
+	}
+	if ErrorKindOf(errs[3]) != ErrorKindNotFound {
+		t.Errorf("Expected the missing post to be not found but got %v", errs[3])
+	}
+	if errs[4] != nil {
+		t.Fatal(errs[4])
+	}
+	if results[4].SourceType != SourceRedditComment || results[4].CreditTitle != "a_comment1" {
+		t.Errorf("Expected the comment by a_comment1 but got %+v", results[4])
+	}
+
+	// More than reddit takes at once is split up
+	transport.calls = nil
+	ids := make([]string, 150)
+	for i := range ids {
+		ids[i] = "p" + string(rune('a'+i/26)) + string(rune('a'+i%26))
+	}
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if _, err := rs.ScrapePostContext(ctx, id); err != nil {
+				t.Error(err)
+			}
+		}(id)
+	}
+	wg.Wait()
+	total := 0
+	for _, call := range transport.calls {
+		if len(call) > RedditInfoBatchSize {
+			t.Errorf("Expected at most %d fullnames per call but sent %d", RedditInfoBatchSize, len(call))
+		}
+		total += len(call)
+	}
+	if len(transport.calls) != 2 || total != len(ids) {
+		t.Errorf("Expected %d fullnames over 2 calls but got %d over %d", len(ids), total, len(transport.calls))
+	}
+}
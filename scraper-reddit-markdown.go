@@ -0,0 +1,152 @@
+package vinscraper
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Reddit's markdown, https://www.reddit.com/wiki/markdown, turned into plain text
+// It's only ever a description so it doesn't have to be perfect, just never show markup
+var (
+	mdFenceRegexp     = regexp.MustCompile("^\\s*(```|~~~)")
+	mdHeadingRegexp   = regexp.MustCompile(`^\s*#{1,6}\s*(.*?)\s*#*\s*$`)
+	mdQuoteRegexp     = regexp.MustCompile(`^\s*(?:>\s*)+`)
+	mdRuleRegexp      = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	mdListRegexp      = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+`)
+	mdTableRuleRegexp = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)+\|?\s*$`)
+
+	mdEscapeRegexp      = regexp.MustCompile("\\\\([\\\\`*_{}\\[\\]()#+\\-.!>~^|<])")
+	mdImageRegexp       = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLinkRegexp        = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	mdAutoLinkRegexp    = regexp.MustCompile(`<((?:https?|ftp)://[^>\s]+)>`)
+	mdSpoilerRegexp     = regexp.MustCompile(`>!(.+?)!<`)
+	mdCodeRegexp        = regexp.MustCompile("`+([^`]+)`+")
+	mdBoldRegexp        = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	mdBoldUnderRegexp   = regexp.MustCompile(`(^|\W)__(.+?)__(\W|$)`)
+	mdItalicRegexp      = regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	mdItalicUnderRegexp = regexp.MustCompile(`(^|\W)_([^_\s](?:[^_]*[^_\s])?)_(\W|$)`)
+	mdStrikeRegexp      = regexp.MustCompile(`~~(.+?)~~`)
+	mdSuperParensRegexp = regexp.MustCompile(`\^\(([^)]*)\)`)
+	mdSuperRegexp       = regexp.MustCompile(`\^(\S)`)
+)
+
+// Escaped characters are swapped for ones from the private use area while the rest is
+// stripped so they're left alone, then swapped back
+const mdEscapeBase = 0xE000
+
+// Paragraphs are separated by a blank line, list items and table rows by a newline
+func redditMarkdownText(md string) string {
+	// Reddit sends selftext with &, < and > escaped, that's the only decoding it gets
+	// Its editor pads empty paragraphs out with a &#x200B; that's typed into the markdown
+	md = html.UnescapeString(strings.ReplaceAll(md, "\r\n", "\n"))
+	md = strings.NewReplacer("&#x200B;", "", "&#x200b;", "").Replace(md)
+
+	var blocks []string
+	var block []string
+	joiner := " "
+	flush := func() {
+		if len(block) > 0 {
+			blocks = append(blocks, strings.Join(block, joiner))
+		}
+		block = nil
+		joiner = " "
+	}
+	add := func(line string, lineJoiner string) {
+		if joiner != lineJoiner {
+			flush()
+			joiner = lineJoiner
+		}
+		if line != "" {
+			block = append(block, line)
+		}
+	}
+
+	inFence := false
+	for _, line := range strings.Split(md, "\n") {
+		if mdFenceRegexp.MatchString(line) {
+			flush()
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			add(strings.TrimRight(line, " \t"), "\n")
+			continue
+		}
+
+		// The |---|---| under a table's header, the rows either side stay together
+		if mdTableRuleRegexp.MatchString(line) {
+			continue
+		}
+		if strings.TrimSpace(line) == "" || mdRuleRegexp.MatchString(line) {
+			flush()
+			continue
+		}
+		if m := mdHeadingRegexp.FindStringSubmatch(line); m != nil {
+			flush()
+			add(markdownInline(m[1]), " ")
+			flush()
+			continue
+		}
+
+		// >!spoilers!< start with a > too
+		if !strings.HasPrefix(strings.TrimSpace(line), ">!") {
+			line = mdQuoteRegexp.ReplaceAllString(line, "")
+		}
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "|") {
+			cells := strings.Split(strings.Trim(trimmed, "|"), "|")
+			for i := range cells {
+				cells[i] = markdownInline(cells[i])
+			}
+			add(strings.Join(cells, " · "), "\n")
+			continue
+		}
+		if loc := mdListRegexp.FindStringIndex(line); loc != nil {
+			add(markdownInline(line[loc[1]:]), "\n")
+			continue
+		}
+		add(markdownInline(line), " ")
+	}
+	flush()
+
+	var text []string
+	for _, b := range blocks {
+		var lines []string
+		for _, l := range strings.Split(b, "\n") {
+			if l = strings.TrimSpace(strings.ReplaceAll(normalizeText(l), "\u200b", "")); l != "" {
+				lines = append(lines, l)
+			}
+		}
+		if len(lines) > 0 {
+			text = append(text, strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(text, "\n\n")
+}
+
+// Strips links, emphasis and the like from a line of markdown
+func markdownInline(s string) string {
+	s = mdEscapeRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		return string(rune(mdEscapeBase + int(m[1])))
+	})
+
+	s = mdCodeRegexp.ReplaceAllString(s, "$1")
+	s = mdImageRegexp.ReplaceAllString(s, "$1")
+	s = mdLinkRegexp.ReplaceAllString(s, "$1")
+	s = mdAutoLinkRegexp.ReplaceAllString(s, "$1")
+	s = mdSpoilerRegexp.ReplaceAllString(s, "$1")
+	s = mdStrikeRegexp.ReplaceAllString(s, "$1")
+	s = mdBoldRegexp.ReplaceAllString(s, "$1")
+	s = mdBoldUnderRegexp.ReplaceAllString(s, "$1$2$3")
+	s = mdItalicRegexp.ReplaceAllString(s, "$1")
+	s = mdItalicUnderRegexp.ReplaceAllString(s, "$1$2$3")
+	s = mdSuperParensRegexp.ReplaceAllString(s, "$1")
+	s = mdSuperRegexp.ReplaceAllString(s, "$1")
+
+	return strings.Map(func(r rune) rune {
+		if r >= mdEscapeBase && r < mdEscapeBase+128 {
+			return r - mdEscapeBase
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,31 @@
+package vinscraper
+
+import "testing"
+
+func TestRedditMarkdownText(t *testing.T) {
+	tests := map[string]string{
+		"Just text": "Just text",
+		"**Bold**, *italic*, ~~gone~~ and `code`":                                "Bold, italic, gone and code",
+		"__bold__ and _italic_ but snake_case_stays":                             "bold and italic but snake_case_stays",
+		"A [link](https://example.com) and ![an image](https://i.redd.it/x.png)": "A link and an image",
+		"See <https://example.com/page>":                                         "See https://example.com/page",
+		"# Heading\nFirst line\nsame paragraph\n\nSecond":                        "Heading\n\nFirst line same paragraph\n\nSecond",
+		"&gt; A quote\n&gt; continued":                                           "A quote continued",
+		"&gt;!spoiler!&lt; here":                                                 "spoiler here",
+		"* one\n* two\n1. three":                                                 "one\ntwo\nthree",
+		"Above\n\n---\n\nBelow":                                                  "Above\n\nBelow",
+		"| Game | Players |\n|---|:--:|\n| Root | 2-4 |":                         "Game · Players\nRoot · 2-4",
+		"```\nfunc **main**() {}\n```":                                           "func **main**() {}",
+		"Not \\*italic\\* and 2\\^3":                                             "Not *italic* and 2^3",
+		"up^(tiny words) and x^2":                                                "uptiny words and x2",
+		"Ben &amp; Jerry's &lt;3":                                                "Ben & Jerry's <3",
+		"Typed &amp;amp; stays":                                                  "Typed &amp; stays",
+		"First\n\n&amp;#x200B;\n\nSecond":                                        "First\n\nSecond",
+		"":                                                                       "",
+	}
+	for md, expected := range tests {
+		if text := redditMarkdownText(md); text != expected {
+			t.Errorf("Expected %q to be %q but got %q", md, expected, text)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package vinscraper
+
+import "strings"
+
+// RedditMedia types
+const (
+	RedditMediaImage = "image"
+	RedditMediaGif   = "gif" // Animated and silent, often an mp4 since reddit converts gifs
+	RedditMediaVideo = "video"
+)
+
+// An image, gif or video in a post, one per gallery item
+type RedditMedia struct {
+	Type     string
+	URL      string // For videos an mp4 that plays without DASH or HLS, but has no sound
+	Width    int
+	Height   int
+	Duration int    `json:",omitempty"` // Seconds
+	DashURL  string `json:",omitempty"`
+	HLSURL   string `json:",omitempty"`
+	// Only gallery items have these
+	Caption     string `json:",omitempty"`
+	OutboundURL string `json:",omitempty"`
+}
+
+// A v.redd.it video
+type RedditVideo struct {
+	FallbackURL string `json:"fallback_url"`
+	DashURL     string `json:"dash_url"`
+	HLSURL      string `json:"hls_url"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Duration    int    `json:"duration"`
+	IsGif       bool   `json:"is_gif"`
+}
+
+// What's in a post's media and secure_media, embeds from other sites are left to EmbedHTML
+type RedditPostMedia struct {
+	RedditVideo *RedditVideo `json:"reddit_video"`
+}
+
+func (v *RedditVideo) media() RedditMedia {
+	m := RedditMedia{
+		Type:     RedditMediaVideo,
+		URL:      redditUnescape(v.FallbackURL),
+		Width:    v.Width,
+		Height:   v.Height,
+		Duration: v.Duration,
+		DashURL:  redditUnescape(v.DashURL),
+		HLSURL:   redditUnescape(v.HLSURL),
+	}
+	if v.IsGif {
+		m.Type = RedditMediaGif
+	}
+	return m
+}
+
+// Reddit keeps items that failed or are still processing in the metadata too
+func (md *RedditMediaMetadata) valid() bool {
+	return md.Status == "valid"
+}
+
+func (md *RedditMediaMetadata) media() RedditMedia {
+	switch md.Kind {
+	case "AnimatedImage":
+		m := RedditMedia{Type: RedditMediaGif, URL: redditUnescape(md.Source.MP4), Width: md.Source.Width, Height: md.Source.Height}
+		if m.URL == "" {
+			m.URL = redditUnescape(md.Source.Gif)
+		}
+		return m
+	case "RedditVideo":
+		m := RedditMedia{Type: RedditMediaVideo, Width: md.Width, Height: md.Height, DashURL: redditUnescape(md.DashURL), HLSURL: redditUnescape(md.HLSURL)}
+		if md.IsGif {
+			m.Type = RedditMediaGif
+		}
+		return m
+	}
+	return RedditMedia{Type: RedditMediaImage, URL: redditUnescape(md.Source.URL), Width: md.Source.Width, Height: md.Source.Height}
+}
+
+// Everything the post shows, in the order it shows it
+func (info *RedditPostInfo) media() []RedditMedia {
+	if len(info.MediaMetadata) > 0 {
+		var media []RedditMedia
+		for _, item := range info.GalleryData.Items {
+			md, ok := info.MediaMetadata[item.MediaId]
+			if !ok || !md.valid() {
+				continue
+			}
+			m := md.media()
+			m.Caption = item.Caption
+			m.OutboundURL = item.OutboundURL
+			media = append(media, m)
+		}
+		return media
+	}
+
+	for _, pm := range []*RedditPostMedia{info.SecureMedia, info.Media} {
+		if pm != nil && pm.RedditVideo != nil {
+			return []RedditMedia{pm.RedditVideo.media()}
+		}
+	}
+	if info.Preview.RedditVideoPreview != nil {
+		return []RedditMedia{info.Preview.RedditVideoPreview.media()}
+	}
+
+	if IsImageLink(info.URL) {
+		m := RedditMedia{Type: RedditMediaImage, URL: info.URL}
+		if strings.HasSuffix(strings.ToLower(info.URL), ".gif") {
+			m.Type = RedditMediaGif
+		}
+		if len(info.Preview.Images) > 0 {
+			image := info.Preview.Images[0]
+			m.Width, m.Height = image.Source.Width, image.Source.Height
+			// The mp4 is far smaller than the gif
+			if mp4 := image.Variants.MP4; m.Type == RedditMediaGif && mp4 != nil && mp4.Source.URL != "" {
+				m.URL = redditUnescape(mp4.Source.URL)
+			}
+		}
+		return []RedditMedia{m}
+	}
+	return nil
+}
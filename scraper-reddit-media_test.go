@@ -0,0 +1,47 @@
+package vinscraper
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestScrapeRedditMedia(t *testing.T) {
+	info := func(id string, data string) (string, string) {
+		return "https://api.reddit.com/api/info?id=t3_" + id, `{"data":{"children":[{"kind":"t3","data":{"id":"` + id + `",` + data + `}}]}}`
+	}
+	pages := fakeTransport{}
+	add := func(url, body string) { pages[url] = body }
+	add(info("video1", `"url":"https://v.redd.it/abc","is_video":true,"secure_media":{"reddit_video":{"fallback_url":"https://v.redd.it/abc/DASH_720.mp4?source=fallback","dash_url":"https://v.redd.it/abc/DASHPlaylist.mpd?a=1&amp;v=1","hls_url":"https://v.redd.it/abc/HLSPlaylist.m3u8?a=1&amp;v=1","width":1280,"height":720,"duration":31,"is_gif":false}}`))
+	add(info("gif1", `"url":"https://v.redd.it/def","is_video":true,"media":{"reddit_video":{"fallback_url":"https://v.redd.it/def/DASH_480.mp4","width":640,"height":480,"duration":4,"is_gif":true}}`))
+	add(info("imgur1", `"url":"https://i.imgur.com/xyz.gifv","preview":{"reddit_video_preview":{"fallback_url":"https://v.redd.it/ghi/DASH_360.mp4","width":480,"height":360,"duration":6,"is_gif":true}}`))
+	add(info("direct1", `"url":"https://i.redd.it/dance.gif","preview":{"images":[{"source":{"url":"https://preview.redd.it/dance.gif?s=1","width":400,"height":300},"variants":{"mp4":{"source":{"url":"https://preview.redd.it/dance.gif?format=mp4&amp;s=2","width":400,"height":300}}}}]}`))
+	add(info("gallery1", `"url":"https://www.reddit.com/gallery/gallery1","gallery_data":{"items":[{"media_id":"m1","id":1,"caption":"The box","outbound_url":"https://example.com/shop"},{"media_id":"m2","id":2},{"media_id":"m3","id":3},{"media_id":"m4","id":4}]},"media_metadata":{"m1":{"status":"valid","e":"Image","s":{"u":"https://preview.redd.it/m1.jpg?width=800&amp;s=a","x":800,"y":600}},"m2":{"status":"valid","e":"AnimatedImage","s":{"gif":"https://i.redd.it/m2.gif","mp4":"https://preview.redd.it/m2.gif?format=mp4&amp;s=b","x":320,"y":240}},"m3":{"status":"valid","e":"RedditVideo","x":1920,"y":1080,"dashUrl":"https://v.redd.it/link/m3/DASHPlaylist.mpd","hlsUrl":"https://v.redd.it/link/m3/HLSPlaylist.m3u8","isGif":false},"m4":{"status":"failed","e":"Image","s":{"u":"https://preview.redd.it/m4.jpg","x":10,"y":10}}}`))
+	scraper := &RedditScraper{HTTPClient: &http.Client{Transport: pages}}
+
+	tests := map[string][]RedditMedia{
+		"video1": {{Type: RedditMediaVideo, URL: "https://v.redd.it/abc/DASH_720.mp4?source=fallback", Width: 1280, Height: 720, Duration: 31,
+			DashURL: "https://v.redd.it/abc/DASHPlaylist.mpd?a=1&v=1", HLSURL: "https://v.redd.it/abc/HLSPlaylist.m3u8?a=1&v=1"}},
+		"gif1":    {{Type: RedditMediaGif, URL: "https://v.redd.it/def/DASH_480.mp4", Width: 640, Height: 480, Duration: 4}},
+		"imgur1":  {{Type: RedditMediaGif, URL: "https://v.redd.it/ghi/DASH_360.mp4", Width: 480, Height: 360, Duration: 6}},
+		"direct1": {{Type: RedditMediaGif, URL: "https://preview.redd.it/dance.gif?format=mp4&s=2", Width: 400, Height: 300}},
+		"gallery1": {
+			{Type: RedditMediaImage, URL: "https://preview.redd.it/m1.jpg?width=800&s=a", Width: 800, Height: 600, Caption: "The box", OutboundURL: "https://example.com/shop"},
+			{Type: RedditMediaGif, URL: "https://preview.redd.it/m2.gif?format=mp4&s=b", Width: 320, Height: 240},
+			{Type: RedditMediaVideo, Width: 1920, Height: 1080, DashURL: "https://v.redd.it/link/m3/DASHPlaylist.mpd", HLSURL: "https://v.redd.it/link/m3/HLSPlaylist.m3u8"},
+		},
+	}
+	for id, expected := range tests {
+		info, err := scraper.Scrape("https://www.reddit.com/r/test/comments/" + id + "/_/")
+		if err != nil {
+			t.Errorf("%s: %s", id, err)
+			continue
+		}
+		if media := info.Meta.(*RedditPostMeta).Media; !reflect.DeepEqual(media, expected) {
+			t.Errorf("%s: expected %+v but got %+v", id, expected, media)
+		}
+		if id == "gallery1" && !reflect.DeepEqual(info.ThumbnailSources, []string{"https://preview.redd.it/m1.jpg?width=800&s=a", "https://i.redd.it/m2.gif"}) {
+			t.Errorf("Expected the image and gif as the gallery's thumbnails, without the failed one, but got %v", info.ThumbnailSources)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package vinscraper
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// https://github.com/reddit-archive/reddit/wiki/OAuth2
+const (
+	RedditTokenURL  = "https://www.reddit.com/api/v1/access_token"
+	RedditOAuthHost = "oauth.reddit.com"
+)
+
+var (
+	ErrRedditNoClientSecret = errors.New("reddit ClientSecret is blank")
+	ErrRedditNoPassword     = errors.New("reddit Password is blank")
+)
+
+// Reddit wants a descriptive User-Agent on every request, token requests included
+type userAgentTransport struct {
+	userAgent string
+	base      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if t.userAgent == "" {
+		return base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return base.RoundTrip(req)
+}
+
+// With a ClientID the scraper authenticates and uses oauth.reddit.com, which allows far more requests
+func (rs *RedditScraper) authenticated() bool {
+	return rs.ClientID != ""
+}
+
+// App only tokens are per app, script tokens are per app and user
+func (rs *RedditScraper) tokenKey() string {
+	return "reddit:" + rs.ClientID + ":" + rs.Username
+}
+
+func (rs *RedditScraper) tokenStore() TokenStore {
+	if rs.TokenStore != nil {
+		return rs.TokenStore
+	}
+	return &rs.tokens
+}
+
+// A token that's good for at least a few more seconds, from the TokenStore if it has one
+// Otherwise a new one is fetched and put in the store for everyone else
+func (rs *RedditScraper) Token(ctx context.Context) (*oauth2.Token, error) {
+	store := rs.tokenStore()
+	key := rs.tokenKey()
+	if token, ok := store.Get(key); ok && token.Valid() {
+		return token, nil
+	}
+
+	rs.tokenMu.Lock()
+	defer rs.tokenMu.Unlock()
+	// It might have been fetched while we waited for the lock
+	if token, ok := store.Get(key); ok && token.Valid() {
+		return token, nil
+	}
+
+	token, err := rs.fetchToken(ctx)
+	if err != nil {
+		return nil, redditTokenError(err)
+	}
+	// We have the token either way, a broken store only costs other processes a fetch
+	store.Set(key, token)
+	return token, nil
+}
+
+// Script apps log in as their user with a password, anything else gets an app only token
+func (rs *RedditScraper) fetchToken(ctx context.Context) (*oauth2.Token, error) {
+	if rs.ClientSecret == "" {
+		return nil, newScrapeError(SourceNameReddit, ErrorKindInvalid, ErrRedditNoClientSecret)
+	}
+
+	client := httpClient(ctx, rs.HTTPClient)
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
+		Transport: &userAgentTransport{rs.UserAgent, client.Transport},
+		Timeout:   client.Timeout,
+	})
+
+	if rs.Username != "" {
+		if rs.Password == "" {
+			return nil, newScrapeError(SourceNameReddit, ErrorKindInvalid, ErrRedditNoPassword)
+		}
+		config := &oauth2.Config{
+			ClientID:     rs.ClientID,
+			ClientSecret: rs.ClientSecret,
+			Endpoint: oauth2.Endpoint{
+				TokenURL:  RedditTokenURL,
+				AuthStyle: oauth2.AuthStyleInHeader,
+			},
+		}
+		return config.PasswordCredentialsToken(ctx, rs.Username, rs.Password)
+	}
+
+	config := &clientcredentials.Config{
+		ClientID:     rs.ClientID,
+		ClientSecret: rs.ClientSecret,
+		TokenURL:     RedditTokenURL,
+		AuthStyle:    oauth2.AuthStyleInHeader,
+	}
+	return config.Token(ctx)
+}
+
+// Reddit answers a wrong password with a 200 and no token, so anything that isn't
+// an HTTP or network error is taken as bad credentials
+func redditTokenError(err error) error {
+	var tokenErr *oauth2.RetrieveError
+	if errors.As(err, &tokenErr) && tokenErr.Response != nil {
+		return httpResponseError(SourceNameReddit, tokenErr.Response, err)
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var se *ScrapeError
+	if errors.As(err, &se) {
+		return se
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return newScrapeError(SourceNameReddit, ErrorKindTransient, err)
+	}
+	return newScrapeError(SourceNameReddit, ErrorKindAuth, err)
+}
+
+// Points an api.reddit.com or www.reddit.com link at oauth.reddit.com and adds the token
+func (rs *RedditScraper) authorize(ctx context.Context, req *http.Request) error {
+	token, err := rs.Token(ctx)
+	if err != nil {
+		return err
+	}
+	switch req.URL.Host {
+	case "api.reddit.com", "reddit.com", "www.reddit.com":
+		u := *req.URL
+		u.Host = RedditOAuthHost
+		req.URL = &u
+		req.Host = ""
+	}
+	token.SetAuthHeader(req)
+	return nil
+}
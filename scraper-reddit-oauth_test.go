@@ -0,0 +1,130 @@
+package vinscraper
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Plays reddit's token endpoint and oauth.reddit.com, handing out tok1, tok2 and so on
+type redditOAuthTransport struct {
+	mu          sync.Mutex
+	tokens      int
+	lastForm    url.Values
+	reject      map[string]bool // Tokens the API pretends were revoked
+	badPassword bool
+	apiRequests int
+}
+
+func (f *redditOAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	respond := func(status int, body string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+
+	if req.URL.String() == RedditTokenURL {
+		id, secret, _ := req.BasicAuth()
+		if id != "app-id" || secret != "app-secret" || req.Header.Get("User-Agent") != "test-agent" {
+			return respond(http.StatusUnauthorized, `{"message": "Unauthorized", "error": 401}`)
+		}
+		b, _ := ioutil.ReadAll(req.Body)
+		f.lastForm, _ = url.ParseQuery(string(b))
+		if f.badPassword {
+			return respond(http.StatusOK, `{"error": "invalid_grant"}`)
+		}
+		f.tokens++
+		return respond(http.StatusOK, `{"access_token": "tok`+string(rune('0'+f.tokens))+`", "token_type": "bearer", "expires_in": 3600}`)
+	}
+
+	if req.URL.Host != RedditOAuthHost {
+		return respond(http.StatusForbidden, `{}`)
+	}
+	f.apiRequests++
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if token == "" || f.reject[token] {
+		return respond(http.StatusUnauthorized, `{"message": "Unauthorized", "error": 401}`)
+	}
+	return respond(http.StatusOK, `{"data":{"children":[{"data":{"author":"someone","id":"jn78c5","title":"Fake Post"}}]}}`)
+}
+
+func TestRedditOAuth(t *testing.T) {
+	transport := &redditOAuthTransport{reject: map[string]bool{}}
+	newScraper := func(store TokenStore) *RedditScraper {
+		return &RedditScraper{
+			HTTPClient:   &http.Client{Transport: transport},
+			UserAgent:    "test-agent",
+			ClientID:     "app-id",
+			ClientSecret: "app-secret",
+			TokenStore:   store,
+		}
+	}
+	link := "https://www.reddit.com/r/boardgames/comments/jn78c5/the_3_minute_board_games_top_100_games_2020/"
+
+	scraper := newScraper(nil)
+	for i := 0; i < 2; i++ {
+		info, err := scraper.Scrape(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Title != "Fake Post" {
+			t.Errorf("Expected 'Fake Post' but got '%s'", info.Title)
+		}
+	}
+	if transport.tokens != 1 || transport.lastForm.Get("grant_type") != "client_credentials" {
+		t.Errorf("Expected 1 app only token but got %d with %v", transport.tokens, transport.lastForm)
+	}
+
+	// Scrapers sharing a store share the token
+	store := NewMemoryTokenStore()
+	if _, err := newScraper(store).Scrape(link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newScraper(store).Scrape(link); err != nil {
+		t.Fatal(err)
+	}
+	if transport.tokens != 2 {
+		t.Errorf("Expected the shared store to need 1 more token but %d were fetched", transport.tokens)
+	}
+
+	// A revoked token is replaced and the request tried again
+	transport.reject["tok2"] = true
+	if _, err := newScraper(store).Scrape(link); err != nil {
+		t.Fatal(err)
+	}
+	if token, _ := store.Get("reddit:app-id:"); transport.tokens != 3 || token.AccessToken != "tok3" {
+		t.Errorf("Expected a new token after the old one was rejected but have %d", transport.tokens)
+	}
+
+	script := newScraper(nil)
+	script.Username = "bot"
+	script.Password = "hunter2"
+	if _, err := script.Scrape(link); err != nil {
+		t.Fatal(err)
+	}
+	if form := transport.lastForm; form.Get("grant_type") != "password" || form.Get("username") != "bot" || form.Get("password") != "hunter2" {
+		t.Errorf("Expected a password grant but sent %v", form)
+	}
+
+	transport.badPassword = true
+	script = newScraper(nil)
+	script.Username = "bot"
+	script.Password = "wrong"
+	if _, err := script.Scrape(link); ErrorKindOf(err) != ErrorKindAuth {
+		t.Errorf("Expected an auth error but got '%v'", err)
+	}
+
+	wrongSecret := newScraper(nil)
+	wrongSecret.ClientSecret = "nope"
+	if _, err := wrongSecret.Scrape(link); ErrorKindOf(err) != ErrorKindAuth {
+		t.Errorf("Expected an auth error but got '%v'", err)
+	}
+}
@@ -0,0 +1,240 @@
+package vinscraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/monstercat/golib/request"
+)
+
+var ErrRedditShareUnresolved = errors.New("reddit share link didn't lead to a post or comment")
+
+type RedditSubredditMeta struct {
+	Name              string // Without the r/
+	Title             string // The long one, like "Board Games: The Tabletop Gaming Community"
+	PublicDescription string
+	Subscribers       int
+	IconURL           string
+	BannerURL         string
+	Over18            bool
+	Created           float64
+
+	// Only for wiki pages
+	WikiPage         string
+	WikiRevisionBy   string
+	WikiRevisionDate float64
+}
+
+type RedditUserMeta struct {
+	Name         string
+	IconURL      string
+	BannerURL    string
+	LinkKarma    int
+	CommentKarma int
+	TotalKarma   int
+	Created      float64
+	Suspended    bool
+	// Whole days old when it was scraped, so it's there for anything reading the JSON
+	AccountAgeDays int
+}
+
+// How long ago the account was made, worked out now so it's right even for cached meta
+func (m *RedditUserMeta) AccountAge() time.Duration {
+	if m.Created == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(int64(m.Created), 0))
+}
+
+// The parts of /user/name/about we use. Suspended users only have Name and IsSuspended
+type RedditUserAbout struct {
+	Name         string  `json:"name"`
+	IconImg      string  `json:"icon_img"`
+	SnoovatarImg string  `json:"snoovatar_img"`
+	LinkKarma    int     `json:"link_karma"`
+	CommentKarma int     `json:"comment_karma"`
+	TotalKarma   int     `json:"total_karma"`
+	CreatedUTC   float64 `json:"created_utc"`
+	IsSuspended  bool    `json:"is_suspended"`
+	// Every user has a profile subreddit, u/name, with their description and banner
+	Subreddit *struct {
+		Title             string `json:"title"`
+		PublicDescription string `json:"public_description"`
+		BannerImg         string `json:"banner_img"`
+		Over18            bool   `json:"over_18"`
+	} `json:"subreddit"`
+}
+
+type RedditUserAboutResponse struct {
+	Data RedditUserAbout `json:"data"`
+}
+
+type RedditWikiPageResponse struct {
+	Data struct {
+		ContentMd    string  `json:"content_md"`
+		RevisionDate float64 `json:"revision_date"`
+		RevisionBy   *struct {
+			Data struct {
+				Name string `json:"name"`
+			} `json:"data"`
+		} `json:"revision_by"`
+	} `json:"data"`
+}
+
+func newRedditSubredditMeta(about *RedditSubredditAbout) *RedditSubredditMeta {
+	meta := &RedditSubredditMeta{
+		Name:              about.DisplayName,
+		Title:             about.Title,
+		PublicDescription: about.PublicDescription,
+		Subscribers:       about.Subscribers,
+		IconURL:           about.CommunityIcon,
+		BannerURL:         about.BannerBackgroundImage,
+		Over18:            about.Over18,
+		Created:           about.CreatedUTC,
+	}
+	if meta.IconURL == "" {
+		meta.IconURL = about.IconImg
+	}
+	if meta.BannerURL == "" {
+		meta.BannerURL = about.BannerImg
+	}
+	if meta.BannerURL == "" {
+		meta.BannerURL = about.MobileBannerImage
+	}
+	return meta
+}
+
+func (rs *RedditScraper) ScrapeSubreddit(ctx context.Context, subreddit string) (*ScrapeInfo, error) {
+	about, err := rs.fetchSubredditAbout(ctx, subreddit)
+	if err != nil {
+		return nil, err
+	}
+	meta := newRedditSubredditMeta(about)
+	if meta.Name == "" {
+		meta.Name = subreddit
+	}
+
+	result := &ScrapeInfo{
+		SourceType:       SourceRedditSubreddit,
+		SourceKey:        meta.Name,
+		Title:            "r/" + meta.Name,
+		Description:      about.PublicDescription,
+		ThumbnailSources: make([]string, 0),
+		Meta:             meta,
+	}
+	if meta.IconURL != "" {
+		result.ThumbnailSources = append(result.ThumbnailSources, meta.IconURL)
+	}
+	rs.setSiteIdentity(ctx, result, &RedditThing{Subreddit: meta.Name, SubredditPrefixed: "r/" + meta.Name})
+	return result, nil
+}
+
+// Credited to whoever edited the page last, the page's text is the Description
+func (rs *RedditScraper) ScrapeWikiPage(ctx context.Context, subreddit string, page string) (*ScrapeInfo, error) {
+	var body RedditWikiPageResponse
+	if err := rs.RedditRequestContext(ctx, "https://api.reddit.com/r/"+url.PathEscape(subreddit)+"/wiki/"+page, &body); err != nil {
+		return nil, err
+	}
+	result, err := rs.ScrapeSubreddit(ctx, subreddit)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := result.Meta.(*RedditSubredditMeta)
+	meta.WikiPage = page
+	meta.WikiRevisionDate = body.Data.RevisionDate
+	if body.Data.RevisionBy != nil {
+		meta.WikiRevisionBy = body.Data.RevisionBy.Data.Name
+		result.CreditTitle = meta.WikiRevisionBy
+		result.CreditURL = getRedditAuthorURL(meta.WikiRevisionBy)
+	}
+	if text := redditMarkdownText(body.Data.ContentMd); text != "" {
+		result.Description = text
+	}
+	result.SourceKey = meta.Name + "/wiki/" + page
+	result.Title = "r/" + meta.Name + " wiki: " + page
+	return result, nil
+}
+
+func (rs *RedditScraper) ScrapeUser(ctx context.Context, username string) (*ScrapeInfo, error) {
+	var body RedditUserAboutResponse
+	if err := rs.RedditRequestContext(ctx, "https://api.reddit.com/user/"+url.PathEscape(username)+"/about", &body); err != nil {
+		return nil, err
+	}
+	about := &body.Data
+	if about.Name == "" {
+		about.Name = username
+	}
+
+	meta := &RedditUserMeta{
+		Name:         about.Name,
+		IconURL:      redditUnescape(about.IconImg),
+		LinkKarma:    about.LinkKarma,
+		CommentKarma: about.CommentKarma,
+		TotalKarma:   about.TotalKarma,
+		Created:      about.CreatedUTC,
+		Suspended:    about.IsSuspended,
+	}
+	meta.AccountAgeDays = int(meta.AccountAge() / (24 * time.Hour))
+	// Snoovatars are the full body ones, the icon is just the head
+	if about.SnoovatarImg != "" {
+		meta.IconURL = redditUnescape(about.SnoovatarImg)
+	}
+
+	result := &ScrapeInfo{
+		SourceType:       SourceRedditUser,
+		SourceKey:        about.Name,
+		Title:            "u/" + about.Name,
+		CreditTitle:      about.Name,
+		CreditURL:        getRedditAuthorURL(about.Name),
+		ThumbnailSources: make([]string, 0),
+		Meta:             meta,
+	}
+	if about.Subreddit != nil {
+		meta.BannerURL = redditUnescape(about.Subreddit.BannerImg)
+		result.Description = about.Subreddit.PublicDescription
+	}
+	if meta.IconURL != "" {
+		result.ThumbnailSources = append(result.ThumbnailSources, meta.IconURL)
+	}
+	rs.setSiteIdentity(ctx, result, &RedditThing{})
+	return result, nil
+}
+
+// Looks at where a /r/subreddit/s/token link redirects to for the post or comment it was made for
+// These only redirect from www.reddit.com so they never go through OAuth
+func (rs *RedditScraper) resolveShareLink(ctx context.Context, link *redditLink) (*redditLink, error) {
+	share := "https://www.reddit.com/r/" + url.PathEscape(link.subreddit) + "/s/" + link.share
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, share, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", rs.UserAgent)
+
+	// The redirect is all we want, not the page it goes to
+	client := *httpClient(ctx, rs.HTTPClient)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, httpResponseError(SourceNameReddit, resp, &request.Error{Status: resp.StatusCode, Message: resp.Status})
+	}
+
+	location, err := resp.Location()
+	if err != nil {
+		return nil, newScrapeError(SourceNameReddit, ErrorKindNotFound, ErrRedditShareUnresolved)
+	}
+	resolved, ok := parseRedditLink(location.String())
+	if !ok || (resolved.kind != redditLinkPost && resolved.kind != redditLinkComment) {
+		return nil, newScrapeError(SourceNameReddit, ErrorKindNotFound, ErrRedditShareUnresolved)
+	}
+	return resolved, nil
+}
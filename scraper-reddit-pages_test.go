@@ -0,0 +1,147 @@
+package vinscraper
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/monstercat/golib/expectm"
+)
+
+func TestScrapeRedditPages(t *testing.T) {
+	scraper := &RedditScraper{
+		HTTPClient: &http.Client{Transport: redirectingTransport{
+			pages: fakeTransport{
+				"https://api.reddit.com/r/boardgames/about":    `{"data":{"display_name":"boardgames","title":"Board Games","public_description":"All about board games","subscribers":4200000,"community_icon":"https://styles.redditmedia.com/icon.png?width=256&amp;s=abc","banner_background_image":"https://styles.redditmedia.com/banner.png?s=def","primary_color":"#0079d3","created_utc":1209254400}}`,
+				"https://api.reddit.com/user/someone/about":    `{"kind":"t2","data":{"name":"someone","icon_img":"https://styles.redditmedia.com/avatar.png?width=256&amp;s=xyz","link_karma":10,"comment_karma":32,"total_karma":42,"created_utc":1500000000,"subreddit":{"public_description":"Just someone","banner_img":""}}}`,
+				"https://api.reddit.com/user/banned/about":     `{"kind":"t2","data":{"name":"banned","is_suspended":true}}`,
+				"https://api.reddit.com/r/boardgames/wiki/faq": `{"kind":"wikipage","data":{"content_md":"# FAQ\n\n**How many players?** Two to four.","revision_date":1600000000,"revision_by":{"kind":"t2","data":{"name":"modperson"}}}}`,
+				"https://api.reddit.com/api/info?id=t3_jn78c5": `{"data":{"children":[{"kind":"t3","data":{"author":"someone","id":"jn78c5","title":"Fake Post"}}]}}`,
+			},
+			redirects: map[string]string{
+				"https://www.reddit.com/r/boardgames/s/AbC123":  "https://www.reddit.com/r/boardgames/comments/jn78c5/fake_post/?share_id=x",
+				"https://www.reddit.com/r/boardgames/s/Nowhere": "https://www.reddit.com/r/boardgames/",
+			},
+		}},
+	}
+
+	tests := []struct {
+		URL      string
+		Expected map[string]interface{}
+	}{
+		{
+			URL: "https://www.reddit.com/r/boardgames/",
+			Expected: map[string]interface{}{
+				"SourceType":       SourceRedditSubreddit,
+				"SourceKey":        "boardgames",
+				"Title":            "r/boardgames",
+				"Description":      "All about board games",
+				"SiteName":         "r/boardgames",
+				"ThemeColor":       "#0079d3",
+				"Meta.Subscribers": float64(4200000),
+				"Meta.IconURL":     "https://styles.redditmedia.com/icon.png?width=256&s=abc",
+				"Meta.BannerURL":   "https://styles.redditmedia.com/banner.png?s=def",
+			},
+		},
+		{
+			URL: "https://old.reddit.com/u/someone",
+			Expected: map[string]interface{}{
+				"SourceType":         SourceRedditUser,
+				"SourceKey":          "someone",
+				"Title":              "u/someone",
+				"Description":        "Just someone",
+				"CreditURL":          "https://www.reddit.com/u/someone",
+				"SiteName":           "Reddit",
+				"ThumbnailSources.0": "https://styles.redditmedia.com/avatar.png?width=256&s=xyz",
+				"Meta.TotalKarma":    42,
+				"Meta.Created":       float64(1500000000),
+			},
+		},
+		{
+			URL: "https://www.reddit.com/r/boardgames/wiki/faq",
+			Expected: map[string]interface{}{
+				"SourceType":          SourceRedditSubreddit,
+				"SourceKey":           "boardgames/wiki/faq",
+				"Title":               "r/boardgames wiki: faq",
+				"Description":         "FAQ\n\nHow many players? Two to four.",
+				"CreditTitle":         "modperson",
+				"Meta.WikiPage":       "faq",
+				"Meta.WikiRevisionBy": "modperson",
+			},
+		},
+		{
+			URL: "https://www.reddit.com/r/boardgames/s/AbC123",
+			Expected: map[string]interface{}{
+				"SourceType": SourceRedditPost,
+				"SourceKey":  "jn78c5",
+				"Title":      "Fake Post",
+				"URL":        "https://www.reddit.com/r/boardgames/s/AbC123",
+			},
+		},
+		// Without a slug the id is still all of jn78c5
+		{
+			URL: "https://www.reddit.com/r/boardgames/comments/jn78c5/",
+			Expected: map[string]interface{}{
+				"SourceKey": "jn78c5",
+				"Title":     "Fake Post",
+			},
+		},
+		{
+			URL: "https://www.reddit.com/comments/jn78c5",
+			Expected: map[string]interface{}{
+				"SourceKey": "jn78c5",
+				"Title":     "Fake Post",
+			},
+		},
+		{
+			URL: "https://redd.it/jn78c5",
+			Expected: map[string]interface{}{
+				"SourceType": SourceRedditPost,
+				"Title":      "Fake Post",
+			},
+		},
+	}
+	for _, test := range tests {
+		info, err := scraper.Scrape(test.URL)
+		if err != nil {
+			t.Errorf("%s: %s", test.URL, err)
+			continue
+		}
+		if err := expectm.CheckJSON(info, (*expectm.ExpectedM)(&test.Expected)); err != nil {
+			t.Errorf("%s: %s", test.URL, err)
+		}
+	}
+
+	info, err := scraper.Scrape("https://www.reddit.com/user/someone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(info.Meta)
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	if days, _ := fields["AccountAgeDays"].(float64); days < 5*365 {
+		t.Errorf("Expected the account age in the JSON but got %s", data)
+	}
+
+	info, err = scraper.Scrape("https://www.reddit.com/user/banned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta := info.Meta.(*RedditUserMeta); !meta.Suspended || meta.AccountAge() != 0 || meta.AccountAgeDays != 0 {
+		t.Errorf("Expected a suspended user with no age but got %+v", meta)
+	}
+	if age := (&RedditUserMeta{Created: 1500000000}).AccountAge(); age < 5*365*24*time.Hour {
+		t.Errorf("Expected an account from 2017 to be years old but it's %s", age)
+	}
+
+	_, err = scraper.Scrape("https://www.reddit.com/r/boardgames/s/Nowhere")
+	if ErrorKindOf(err) != ErrorKindNotFound || !errors.Is(err, ErrRedditShareUnresolved) {
+		t.Errorf("Expected a share link to a subreddit to be unresolved but got %v", err)
+	}
+	_, err = scraper.Scrape("https://www.reddit.com/user/nobody")
+	if ErrorKindOf(err) != ErrorKindNotFound {
+		t.Errorf("Expected a missing user to be not found but got %v", err)
+	}
+}
@@ -1,24 +1,125 @@
 package vinscraper
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"io/ioutil"
+	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/monstercat/golib/request"
 )
 
 var (
 	ErrRedditNoChildren = errors.New("no children in reddit")
+	ErrRedditBadURL     = errors.New("not a reddit link we know")
 )
 
 const (
-	SourceRedditPost    = "reddit_post"
-	SourceRedditComment = "reddit_comment"
+	SourceRedditPost      = "reddit_post"
+	SourceRedditComment   = "reddit_comment"
+	SourceRedditSubreddit = "reddit_subreddit" // Wiki pages too, their SourceKey is subreddit/wiki/page
+	SourceRedditUser      = "reddit_user"
 )
 
-var redditUrlRegexp = "\\/comments\\/([%a-zA-Z0-9]+)\\/?[[%a-zA-Z0-9\\_]+?\\/([%a-zA-Z0-9]+)?"
+// For posts in subreddits without an icon of their own
+const (
+	RedditIconURL    = "https://www.redditstatic.com/desktop2x/img/favicon/android-icon-192x192.png"
+	RedditThemeColor = "#FF4500"
+)
+
+const (
+	// How many subreddits' icons and colors are kept at once
+	RedditSubredditCacheSize = 1000
+	// How long a subreddit whose about page failed, like a private or banned one, is left alone
+	RedditSubredditErrorTTL = time.Hour
+)
+
+// /r/subreddit/comments/post/slug/comment/ with everything but the post id optional
+// Ids are lowercase base36 but links with them uppercased still work on reddit
+var redditCommentsRegexp = regexp.MustCompile(`(?i)^/(?:r/[^/]+/)?comments/([a-z0-9]+)(?:/[^/]*(?:/([a-z0-9]+))?)?/?$`)
+
+// The other kinds of reddit links, matched against the path
+var (
+	redditShareRegexp         = regexp.MustCompile(`^/r/([A-Za-z0-9_]+)/s/([A-Za-z0-9]+)/?$`)
+	redditWikiRegexp          = regexp.MustCompile(`^/r/([A-Za-z0-9_]+)/wiki(?:/([A-Za-z0-9_/-]*?))?/?$`)
+	redditUserRegexp          = regexp.MustCompile(`^/(?:u|user)/([A-Za-z0-9_-]+)(?:/(?:overview|submitted|comments))?/?$`)
+	redditSubredditPageRegexp = regexp.MustCompile(`^/r/([A-Za-z0-9_]+)(?:/(?:hot|new|top|rising|controversial))?/?$`)
+	redditShortRegexp         = regexp.MustCompile(`^/([A-Za-z0-9]+)/?$`)
+)
+
+// Listings that look like subreddits but have no about page
+var redditPseudoSubreddits = map[string]bool{"all": true, "popular": true, "friends": true, "mod": true}
+
+type redditLinkKind int
+
+const (
+	redditLinkPost redditLinkKind = iota
+	redditLinkComment
+	redditLinkSubreddit
+	redditLinkUser
+	redditLinkWiki
+	redditLinkShare
+)
+
+// What a reddit link points at. Only the fields its kind needs are set
+type redditLink struct {
+	kind      redditLinkKind
+	post      string
+	comment   string
+	subreddit string
+	user      string
+	wikiPage  string
+	share     string
+}
+
+func parseRedditLink(link string) (*redditLink, bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "redd.it" || host == "www.redd.it" {
+		if m := redditShortRegexp.FindStringSubmatch(u.Path); m != nil {
+			return &redditLink{kind: redditLinkPost, post: strings.ToLower(m[1])}, true
+		}
+		return nil, false
+	}
+	// Not notreddit.com
+	if host != "reddit.com" && !strings.HasSuffix(host, ".reddit.com") {
+		return nil, false
+	}
+
+	if result := redditCommentsRegexp.FindStringSubmatch(u.Path); result != nil {
+		post, comment := strings.ToLower(result[1]), strings.ToLower(result[2])
+		if comment == "" {
+			return &redditLink{kind: redditLinkPost, post: post}, true
+		}
+		return &redditLink{kind: redditLinkComment, post: post, comment: comment}, true
+	}
+	if m := redditShareRegexp.FindStringSubmatch(u.Path); m != nil {
+		return &redditLink{kind: redditLinkShare, subreddit: m[1], share: m[2]}, true
+	}
+	if m := redditWikiRegexp.FindStringSubmatch(u.Path); m != nil {
+		page := strings.Trim(m[2], "/")
+		if page == "" {
+			page = "index"
+		}
+		return &redditLink{kind: redditLinkWiki, subreddit: m[1], wikiPage: page}, true
+	}
+	if m := redditUserRegexp.FindStringSubmatch(u.Path); m != nil {
+		return &redditLink{kind: redditLinkUser, user: m[1]}, true
+	}
+	if m := redditSubredditPageRegexp.FindStringSubmatch(u.Path); m != nil && !redditPseudoSubreddits[strings.ToLower(m[1])] {
+		return &redditLink{kind: redditLinkSubreddit, subreddit: m[1]}, true
+	}
+	return nil, false
+}
 
 // Used as return data, can be our own structure
 type RedditThingMeta struct {
@@ -34,9 +135,18 @@ type RedditThingMeta struct {
 
 type RedditPostMeta struct {
 	RedditThingMeta
-	Crossposts []RedditThing
-	Spoiler    bool
-	URL        string
+	Crossposts    []RedditThing
+	Spoiler       bool
+	URL           string
+	Selftext      string // Markdown, the plain text version is the Description
+	Score         int
+	UpvoteRatio   float64
+	NumComments   int
+	Over18        bool
+	LinkFlairText string
+	IsVideo       bool
+	Domain        string // self.subreddit for text posts
+	Media         []RedditMedia
 }
 type RedditCommentMeta struct {
 	RedditThingMeta
@@ -44,11 +154,22 @@ type RedditCommentMeta struct {
 }
 type RedditMediaMetadata struct {
 	Status string
+	Kind   string `json:"e"` // Image, AnimatedImage or RedditVideo
 	Source struct {
 		Width  int    `json:"x"`
 		Height int    `json:"y"`
 		URL    string `json:"u"`
+		// AnimatedImage has these instead of u
+		Gif string `json:"gif"`
+		MP4 string `json:"mp4"`
 	} `json:"s"`
+
+	// RedditVideo has these instead of s
+	Width   int    `json:"x"`
+	Height  int    `json:"y"`
+	DashURL string `json:"dashUrl"`
+	HLSURL  string `json:"hlsUrl"`
+	IsGif   bool   `json:"isGif"`
 }
 
 // Comment or Posts are "things" to reddit
@@ -64,10 +185,34 @@ type RedditThing struct {
 	SubredditPrefixed string  `json:"subreddit_name_prefixed"`
 }
 
+// Reddit's resized copies of a post's image, or a frame of its video
+type RedditPreviewImage struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type RedditPreview struct {
+	Images []struct {
+		Source      RedditPreviewImage   `json:"source"`
+		Resolutions []RedditPreviewImage `json:"resolutions"` // Smallest first
+		// Gifs come with an mp4 copy too
+		Variants struct {
+			MP4 *struct {
+				Source RedditPreviewImage `json:"source"`
+			} `json:"mp4"`
+		} `json:"variants"`
+	} `json:"images"`
+	// Reddit's mp4 copy of a gif or video hosted elsewhere, like imgur
+	RedditVideoPreview *RedditVideo `json:"reddit_video_preview"`
+}
+
 type RedditGalleryData struct {
 	Items []struct {
-		Id      int    `json:"id"`
-		MediaId string `json:"media_id"`
+		Id          int    `json:"id"`
+		MediaId     string `json:"media_id"`
+		Caption     string `json:"caption"`
+		OutboundURL string `json:"outbound_url"`
 	} `json:"items"`
 }
 
@@ -82,6 +227,17 @@ type RedditPostInfo struct {
 	Spoiler       bool                           `json:"spoiler"`
 	Title         string                         `json:"title"`
 	URL           string                         `json:"url"`
+	Selftext      string                         `json:"selftext"`
+	Score         int                            `json:"score"`
+	UpvoteRatio   float64                        `json:"upvote_ratio"`
+	NumComments   int                            `json:"num_comments"`
+	Over18        bool                           `json:"over_18"`
+	LinkFlairText string                         `json:"link_flair_text"`
+	IsVideo       bool                           `json:"is_video"`
+	Domain        string                         `json:"domain"`
+	Preview       RedditPreview                  `json:"preview"`
+	SecureMedia   *RedditPostMedia               `json:"secure_media"`
+	Media         *RedditPostMedia               `json:"media"`
 }
 
 type RedditCommentInfo struct {
@@ -93,64 +249,172 @@ type RedditCommentInfo struct {
 }
 
 type RedditScraper struct {
-	UserAgent string
+	HTTPClient *http.Client // Overrides the client from Scraping
+	UserAgent  string
+
+	// Set ClientID and ClientSecret to use OAuth, which reddit allows far more requests with
+	// Without Username the scraper gets an app only token, with it it logs in as that user
+	// like a script app, https://www.reddit.com/prefs/apps
+	ClientID     string
+	ClientSecret string
+	Username     string
+	Password     string
+	// Where tokens are kept, share one between processes so they share a token
+	// Defaults to a store of the scraper's own
+	TokenStore TokenStore
+	// How long a post or comment lookup waits for others to share an /api/info call with,
+	// up to RedditInfoBatchSize of them. 0 looks each one up on its own, try DefaultRedditBatchWindow
+	BatchWindow time.Duration
+	// Don't look up subreddits' icons and colors, saving a request per new subreddit at the
+	// cost of posts showing reddit's own instead
+	SkipSubredditIdentity bool
+
+	tokenMu sync.Mutex
+	tokens  MemoryTokenStore
+
+	// Subreddits' icons and colors, they hardly ever change so each one is only looked up once
+	subredditsMu sync.Mutex
+	subreddits   map[string]*redditSubredditEntry
+
+	batchMu sync.Mutex
+	batch   *redditBatch
 }
 
-func (rs *RedditScraper) WantsURL(link string) bool {
-	u, _ := url.Parse(link)
-	host := strings.ToLower(u.Hostname())
-	if !strings.HasSuffix(host, "reddit.com") {
-		return false
-	}
-	r, err := regexp.Compile(redditUrlRegexp)
-	if err != nil {
-		return false
-	}
-	result := r.FindStringSubmatch(link)
+// The parts of /r/subreddit/about we use
+type RedditSubredditAbout struct {
+	CommunityIcon string `json:"community_icon"`
+	IconImg       string `json:"icon_img"`
+	PrimaryColor  string `json:"primary_color"`
+	KeyColor      string `json:"key_color"`
+
+	DisplayName           string  `json:"display_name"`
+	DisplayNamePrefixed   string  `json:"display_name_prefixed"`
+	Title                 string  `json:"title"`
+	PublicDescription     string  `json:"public_description"`
+	Subscribers           int     `json:"subscribers"`
+	BannerBackgroundImage string  `json:"banner_background_image"`
+	BannerImg             string  `json:"banner_img"`
+	MobileBannerImage     string  `json:"mobile_banner_image"`
+	Over18                bool    `json:"over18"`
+	CreatedUTC            float64 `json:"created_utc"`
+}
 
-	if len(result) < 3 {
-		return false
-	}
-	return true
+type RedditSubredditAboutResponse struct {
+	Data RedditSubredditAbout `json:"data"`
+}
+
+// Posts, comments, subreddits, users, wiki pages and redd.it and /s/ share links
+func (rs *RedditScraper) WantsURL(link string) bool {
+	_, ok := parseRedditLink(link)
+	return ok
 }
 
 func (rs *RedditScraper) Scrape(urlS string) (*ScrapeInfo, error) {
-	r, err := regexp.Compile(redditUrlRegexp)
+	return rs.ScrapeContext(context.Background(), urlS)
+}
+
+func (rs *RedditScraper) ScrapeContext(ctx context.Context, urlS string) (*ScrapeInfo, error) {
+	info, err := rs.scrape(ctx, urlS)
 	if err != nil {
-		return nil, err
+		return nil, wrapScrapeError(SourceNameReddit, err)
 	}
-	result := r.FindStringSubmatch(urlS)
+	return info, nil
+}
 
-	var info *ScrapeInfo
+func (rs *RedditScraper) scrape(ctx context.Context, urlS string) (*ScrapeInfo, error) {
+	link, ok := parseRedditLink(urlS)
+	if !ok {
+		return nil, newScrapeError(SourceNameReddit, ErrorKindInvalid, ErrRedditBadURL)
+	}
 
-	// Reddit urls look kind of like /r/subreddit/1235234/comments when it's a link to a post
-	// and a link to a comment will append the comment id
-	// So if no second id is found, then this is a post link and we scrape a post
-	if result[2] == "" {
-		info, err = rs.ScrapePost(result[1])
+	// Share links don't say what they're for, reddit has to redirect us first
+	if link.kind == redditLinkShare {
+		var err error
+		link, err = rs.resolveShareLink(ctx, link)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		if IsImageLink(info.URL) {
-			info.ThumbnailSources = []string{info.URL}
-		}
-	} else {
-		info, err = rs.ScrapeComment(result[2])
-		if err != nil {
-			return nil, err
-		}
+	var info *ScrapeInfo
+	var err error
+	switch link.kind {
+	// Reddit urls look kind of like /r/subreddit/1235234/comments when it's a link to a post
+	// and a link to a comment will append the comment id
+	case redditLinkPost:
+		info, err = rs.ScrapePostContext(ctx, link.post)
+	case redditLinkComment:
+		info, err = rs.ScrapeCommentContext(ctx, link.comment)
+	case redditLinkSubreddit:
+		info, err = rs.ScrapeSubreddit(ctx, link.subreddit)
+	case redditLinkUser:
+		info, err = rs.ScrapeUser(ctx, link.user)
+	case redditLinkWiki:
+		info, err = rs.ScrapeWikiPage(ctx, link.subreddit, link.wikiPage)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	info.URL = urlS
 	return info, nil
 }
 
+// Old, new, np, www and redd.it links all become www.reddit.com ones. Posts and comments
+// lose their subreddit and slug, since reddit finds them by id alone and both can change, and
+// everything loses its query, which only ever sorts, tracks or sets how much context to show
+// Subreddit and user names are case insensitive so they're lowercased
+func (rs *RedditScraper) StandardizeURL(link string) string {
+	parsed, ok := parseRedditLink(link)
+	if !ok {
+		return ""
+	}
+
+	var path string
+	switch parsed.kind {
+	case redditLinkPost, redditLinkComment:
+		path = "/comments/" + parsed.post + "/_/"
+		if parsed.comment != "" {
+			path += parsed.comment + "/"
+		}
+	case redditLinkSubreddit:
+		path = "/r/" + strings.ToLower(parsed.subreddit) + "/"
+	case redditLinkUser:
+		path = "/user/" + strings.ToLower(parsed.user) + "/"
+	case redditLinkWiki:
+		path = "/r/" + strings.ToLower(parsed.subreddit) + "/wiki/" + parsed.wikiPage + "/"
+	case redditLinkShare:
+		path = "/r/" + strings.ToLower(parsed.subreddit) + "/s/" + parsed.share
+	}
+
+	u := url.URL{Scheme: "https", Host: "www.reddit.com", Path: path}
+	return u.String()
+}
+
+// Posts and comments are known by their id, however the link to them is written
+func (rs *RedditScraper) SourceOf(link string) (SourceType, string, bool) {
+	parsed, ok := parseRedditLink(link)
+	if !ok {
+		return "", "", false
+	}
+	switch parsed.kind {
+	case redditLinkPost:
+		return SourceRedditPost, parsed.post, true
+	case redditLinkComment:
+		return SourceRedditComment, parsed.comment, true
+	}
+	return "", "", false
+}
+
 func (rs *RedditScraper) ScrapePost(postId string) (*ScrapeInfo, error) {
+	return rs.ScrapePostContext(context.Background(), postId)
+}
+
+func (rs *RedditScraper) ScrapePostContext(ctx context.Context, postId string) (*ScrapeInfo, error) {
 	src := &RedditPostSource{
 		ID: postId,
 	}
-	info, err := src.GetPostInfo(rs)
+	info, err := src.GetPostInfoContext(ctx, rs)
 	if err != nil {
 		return nil, err
 	}
@@ -158,48 +422,84 @@ func (rs *RedditScraper) ScrapePost(postId string) (*ScrapeInfo, error) {
 	result := info.BasicScrapeInfo()
 	result.SourceType = SourceRedditPost
 	result.Title = info.Title
+	result.Description = redditMarkdownText(info.Selftext)
 	result.URL = info.URL
+	rs.setSiteIdentity(ctx, result, &info.RedditThing)
 
 	result.Meta = &RedditPostMeta{
 		Crossposts:      info.Crossposts,
 		RedditThingMeta: info.RedditThing.ToMeta(),
 		Spoiler:         info.Spoiler,
 		URL:             info.URL,
+		Selftext:        info.Selftext,
+		Score:           info.Score,
+		UpvoteRatio:     info.UpvoteRatio,
+		NumComments:     info.NumComments,
+		Over18:          info.Over18,
+		LinkFlairText:   info.LinkFlairText,
+		IsVideo:         info.IsVideo,
+		Domain:          info.Domain,
+		Media:           info.media(),
 	}
 
 	result.ThumbnailSources = make([]string, 0)
 	// If metadata has items in it then this reddit post is a gallery
 	if len(info.MediaMetadata) > 0 {
-		mediaThumbs := map[string]string{}
-
-		// This data is always sorted randomly
-		// Golang automatically randomizes JSON key order because the order
-		// can't be guaranteed
-		for k, v := range info.MediaMetadata {
-			thumb := strings.ReplaceAll(v.Source.URL, "&amp;", "&")
-			mediaThumbs[k] = thumb
-			// For some reason reddit does this encoding to their URL params
-		}
-
-		// The gallery data IS in order, however
+		// The metadata is a map so it's in no order, the gallery data is in order though
 		for _, v := range info.GalleryData.Items {
-			thumb, ok := mediaThumbs[v.MediaId]
-			if ok {
-				result.ThumbnailSources = append(result.ThumbnailSources, thumb)
-			} else {
+			md, ok := info.MediaMetadata[v.MediaId]
+			if !ok {
 				return nil, errors.New("could not find media from gallery with id: " + v.MediaId)
 			}
+			// Same as Meta's media, items that failed or are still processing aren't shown
+			if !md.valid() {
+				continue
+			}
+			// Animated ones only have the gif, and videos nothing we can show
+			thumb := md.Source.URL
+			if thumb == "" {
+				thumb = md.Source.Gif
+			}
+			// For some reason reddit does this encoding to their URL params
+			thumb = strings.ReplaceAll(thumb, "&amp;", "&")
+			if thumb != "" {
+				result.ThumbnailSources = append(result.ThumbnailSources, thumb)
+			}
+		}
+	} else if IsImageLink(info.URL) {
+		result.ThumbnailSources = []string{info.URL}
+	} else {
+		// Links, videos and the like get reddit's previews, biggest first
+		for _, image := range info.Preview.Images {
+			if image.Source.URL == "" {
+				continue
+			}
+			result.Thumbnails = append(result.Thumbnails, image.Source.thumbnail())
+			for i := len(image.Resolutions) - 1; i >= 0; i-- {
+				result.Thumbnails = append(result.Thumbnails, image.Resolutions[i].thumbnail())
+			}
+		}
+		for _, thumb := range result.Thumbnails {
+			result.ThumbnailSources = append(result.ThumbnailSources, thumb.URL)
 		}
 	}
 
 	return result, nil
 }
 
+func (p RedditPreviewImage) thumbnail() Thumbnail {
+	return Thumbnail{URL: redditUnescape(p.URL), Width: p.Width, Height: p.Height}
+}
+
 func (rs *RedditScraper) ScrapeComment(postId string) (*ScrapeInfo, error) {
+	return rs.ScrapeCommentContext(context.Background(), postId)
+}
+
+func (rs *RedditScraper) ScrapeCommentContext(ctx context.Context, postId string) (*ScrapeInfo, error) {
 	src := &RedditCommentSource{
 		ID: postId,
 	}
-	info, err := src.GetCommentInfo(rs)
+	info, err := src.GetCommentInfoContext(ctx, rs)
 	if err != nil {
 		return nil, err
 	}
@@ -207,6 +507,7 @@ func (rs *RedditScraper) ScrapeComment(postId string) (*ScrapeInfo, error) {
 	result := info.BasicScrapeInfo()
 	result.SourceType = SourceRedditComment
 	result.Title = "Comment by " + info.Author
+	rs.setSiteIdentity(ctx, result, &info.RedditThing)
 
 	result.Meta = &RedditCommentMeta{
 		RedditThingMeta: info.RedditThing.ToMeta(),
@@ -245,15 +546,25 @@ func (p *RedditPostSource) GetKey() string {
 }
 
 func (p *RedditPostSource) GetPostInfo(rs *RedditScraper) (*RedditPostInfo, error) {
-	var body RedditPostInfoResponse
-	params := request.Params{
-		Url: "https://api.reddit.com/api/info?id=t3_" + p.ID,
+	return p.GetPostInfoContext(context.Background(), rs)
+}
+
+func (p *RedditPostSource) GetPostInfoContext(ctx context.Context, rs *RedditScraper) (*RedditPostInfo, error) {
+	if rs.BatchWindow > 0 {
+		data, err := rs.batchInfo(ctx, "t3_"+p.ID)
+		if err != nil {
+			return nil, err
+		}
+		var dat RedditPostInfo
+		return &dat, json.Unmarshal(data, &dat)
 	}
-	if err := rs.RedditRequest(&params, nil, &body); err != nil {
+
+	var body RedditPostInfoResponse
+	if err := rs.RedditRequestContext(ctx, "https://api.reddit.com/api/info?id=t3_"+p.ID, &body); err != nil {
 		return nil, err
 	}
 	if len(body.Data.Children) == 0 {
-		return nil, ErrRedditNoChildren
+		return nil, newScrapeError(SourceNameReddit, ErrorKindNotFound, ErrRedditNoChildren)
 	}
 	dat := body.Data.Children[0].Data
 	return &dat, nil
@@ -263,29 +574,179 @@ func (p *RedditCommentSource) GetKey() string {
 	return p.ID
 }
 
+// The golib form from before RedditRequestContext, for requests with their own method, headers
+// or payload. It gets the User-Agent and, with OAuth set up, the token and oauth.reddit.com
+// but not the HTTP client or rate limiter from a context
 func (rs *RedditScraper) RedditRequest(params *request.Params, payload interface{}, body interface{}) error {
 	if params.Headers == nil {
 		params.Headers = make(map[string]string)
 	}
 	params.Headers["User-agent"] = rs.UserAgent
+	if rs.authenticated() {
+		req, err := http.NewRequest(http.MethodGet, params.Url, nil)
+		if err != nil {
+			return err
+		}
+		if err := rs.authorize(context.Background(), req); err != nil {
+			return err
+		}
+		params.Url = req.URL.String()
+		params.Headers["Authorization"] = req.Header.Get("Authorization")
+	}
 	return request.Request(params, payload, body)
 }
 
+// Makes a GET request to the reddit API and decodes the JSON response into body
+// Error statuses come back as a *ScrapeError wrapping a *request.Error like golib used to return
+// With OAuth set up the request goes to oauth.reddit.com with a token instead
+func (rs *RedditScraper) RedditRequestContext(ctx context.Context, link string, body interface{}) error {
+	err := rs.redditRequest(ctx, link, body)
+	var se *ScrapeError
+	// The token was revoked or expired early, a new one might work
+	if rs.authenticated() && errors.As(err, &se) && se.Status == http.StatusUnauthorized {
+		rs.tokenStore().Delete(rs.tokenKey())
+		err = rs.redditRequest(ctx, link, body)
+	}
+	return err
+}
+
+func (rs *RedditScraper) redditRequest(ctx context.Context, link string, body interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", rs.UserAgent)
+	if rs.authenticated() {
+		if err := rs.authorize(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	resp, err := httpClient(ctx, rs.HTTPClient).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return httpResponseError(SourceNameReddit, resp, &request.Error{Status: resp.StatusCode, Message: string(msg)})
+	}
+
+	return json.NewDecoder(resp.Body).Decode(body)
+}
+
 func (p *RedditCommentSource) GetCommentInfo(rs *RedditScraper) (*RedditCommentInfo, error) {
-	var body RedditCommentInfoResponse
-	params := request.Params{
-		Url: "https://api.reddit.com/api/info?id=t1_" + p.ID,
+	return p.GetCommentInfoContext(context.Background(), rs)
+}
+
+func (p *RedditCommentSource) GetCommentInfoContext(ctx context.Context, rs *RedditScraper) (*RedditCommentInfo, error) {
+	if rs.BatchWindow > 0 {
+		data, err := rs.batchInfo(ctx, "t1_"+p.ID)
+		if err != nil {
+			return nil, err
+		}
+		var dat RedditCommentInfo
+		return &dat, json.Unmarshal(data, &dat)
 	}
-	if err := rs.RedditRequest(&params, nil, &body); err != nil {
+
+	var body RedditCommentInfoResponse
+	if err := rs.RedditRequestContext(ctx, "https://api.reddit.com/api/info?id=t1_"+p.ID, &body); err != nil {
 		return nil, err
 	}
 	if len(body.Data.Children) == 0 {
-		return nil, ErrRedditNoChildren
+		return nil, newScrapeError(SourceNameReddit, ErrorKindNotFound, ErrRedditNoChildren)
 	}
 	dat := body.Data.Children[0].Data
 	return &dat, nil
 }
 
+// A subreddit's about page or why it couldn't be had
+type redditSubredditEntry struct {
+	about     *RedditSubredditAbout
+	err       error
+	expiresAt time.Time // Only failures expire
+}
+
+// Looks up a subreddit's icon and colors, caching them for next time
+// Failures are cached for RedditSubredditErrorTTL unless they might work on a retry
+func (rs *RedditScraper) SubredditAbout(ctx context.Context, subreddit string) (*RedditSubredditAbout, error) {
+	rs.subredditsMu.Lock()
+	entry, ok := rs.subreddits[strings.ToLower(subreddit)]
+	rs.subredditsMu.Unlock()
+	if ok && (entry.err == nil || time.Now().Before(entry.expiresAt)) {
+		return entry.about, entry.err
+	}
+	return rs.fetchSubredditAbout(ctx, subreddit)
+}
+
+// Always asks reddit, for when the subscriber count should be current, and updates the cache
+func (rs *RedditScraper) fetchSubredditAbout(ctx context.Context, subreddit string) (*RedditSubredditAbout, error) {
+	var body RedditSubredditAboutResponse
+	if err := rs.RedditRequestContext(ctx, "https://api.reddit.com/r/"+url.PathEscape(subreddit)+"/about", &body); err != nil {
+		if !IsRetryable(err) && ctx.Err() == nil {
+			rs.storeSubreddit(subreddit, &redditSubredditEntry{err: err, expiresAt: time.Now().Add(RedditSubredditErrorTTL)})
+		}
+		return nil, err
+	}
+	about := &body.Data
+	about.CommunityIcon = redditUnescape(about.CommunityIcon)
+	about.IconImg = redditUnescape(about.IconImg)
+	about.BannerBackgroundImage = redditUnescape(about.BannerBackgroundImage)
+	about.BannerImg = redditUnescape(about.BannerImg)
+	about.MobileBannerImage = redditUnescape(about.MobileBannerImage)
+
+	rs.storeSubreddit(subreddit, &redditSubredditEntry{about: about})
+	return about, nil
+}
+
+// Once there are RedditSubredditCacheSize subreddits an arbitrary one makes way,
+// they're cheap enough to look up again
+func (rs *RedditScraper) storeSubreddit(subreddit string, entry *redditSubredditEntry) {
+	key := strings.ToLower(subreddit)
+	rs.subredditsMu.Lock()
+	defer rs.subredditsMu.Unlock()
+	if rs.subreddits == nil {
+		rs.subreddits = make(map[string]*redditSubredditEntry)
+	}
+	if _, ok := rs.subreddits[key]; !ok && len(rs.subreddits) >= RedditSubredditCacheSize {
+		for k := range rs.subreddits {
+			delete(rs.subreddits, k)
+			break
+		}
+	}
+	rs.subreddits[key] = entry
+}
+
+// Reddit's own icon and color, swapped for the subreddit's when it has them
+func (rs *RedditScraper) setSiteIdentity(ctx context.Context, info *ScrapeInfo, thing *RedditThing) {
+	info.SiteName = "Reddit"
+	info.SiteIconURL = RedditIconURL
+	info.ThemeColor = RedditThemeColor
+	if thing.SubredditPrefixed != "" {
+		info.SiteName = thing.SubredditPrefixed
+	}
+	if thing.Subreddit == "" || rs.SkipSubredditIdentity {
+		return
+	}
+
+	// Not worth failing the whole scrape over an icon
+	about, err := rs.SubredditAbout(ctx, thing.Subreddit)
+	if err != nil {
+		return
+	}
+	if about.CommunityIcon != "" {
+		info.SiteIconURL = about.CommunityIcon
+	} else if about.IconImg != "" {
+		info.SiteIconURL = about.IconImg
+	}
+	if about.PrimaryColor != "" {
+		info.ThemeColor = about.PrimaryColor
+	} else if about.KeyColor != "" {
+		info.ThemeColor = about.KeyColor
+	}
+}
+
 func (rt *RedditThing) BasicScrapeInfo() *ScrapeInfo {
 	item := &ScrapeInfo{
 		CreditTitle: rt.Author,
@@ -307,6 +768,11 @@ func (rt *RedditThing) ToMeta() RedditThingMeta {
 	}
 }
 
+// Reddit HTML escapes the query strings in its image urls
+func redditUnescape(link string) string {
+	return strings.ReplaceAll(link, "&amp;", "&")
+}
+
 func getRedditAuthorURL(username string) string {
 	return "https://www.reddit.com/u/" + username
 }
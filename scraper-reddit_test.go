@@ -3,11 +3,16 @@ package vinscraper
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/monstercat/golib/expectm"
+	"github.com/monstercat/golib/request"
 )
 
 func getTestRedditScraper(t *testing.T) *RedditScraper {
@@ -26,10 +31,29 @@ func TestScrapeRedditWants(t *testing.T) {
 	tests := CreateWantTests(scraper, []string{
 		"https://www.reddit.com/r/boardgames/comments/jn78c5/the_3_minute_board_games_top_100_games_2020/",
 		"https://old.reddit.com/r/Warhammer40k/comments/jnaol2/my_halloween_costume_made_in_3_days_salamander/gb077ru?utm_source=share&utm_medium=web2x&context=3",
+		"https://www.reddit.com/r/boardgames/comments/jn78c5/",
+		"https://www.reddit.com/r/boardgames/comments/jn78c5",
+		"https://www.reddit.com/comments/jn78c5",
+		"https://www.reddit.com/r/boardgames",
+		"https://old.reddit.com/r/boardgames/top/?t=year",
+		"https://www.reddit.com/u/someone",
+		"https://www.reddit.com/user/someone/submitted/",
+		"https://www.reddit.com/r/boardgames/wiki/faq",
+		"https://www.reddit.com/r/boardgames/wiki/",
+		"https://redd.it/jn78c5",
+		"https://www.reddit.com/r/boardgames/s/AbC123xyz",
+		"https://www.reddit.com/r/boardgames/comments/JN78C5/",
+		"https://reddit.com/r/boardgames",
 	}, []string{
+		"https://notreddit.com/r/boardgames",
+		"https://evilreddit.com/r/boardgames/comments/jn78c5/",
+		"https://www.evilreddit.com/u/someone",
 		"https://wordpress.org/showcase/ladybird-education/",
 		"https://google.com",
 		"not a real url",
+		"https://www.reddit.com/r/all",
+		"https://www.reddit.com/settings",
+		"https://redd.it/a/b",
 	}...)
 
 	if err := RunTests(tests); err != nil {
@@ -111,3 +135,142 @@ func TestScrapeRedditComment(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestScrapeRedditPostFields(t *testing.T) {
+	scraper := &RedditScraper{
+		HTTPClient: &http.Client{Transport: fakeTransport{
+			"https://api.reddit.com/api/info?id=t3_self1": `{"data":{"children":[{"kind":"t3","data":{"id":"self1","title":"Text post","selftext":"**Hello** &amp; [welcome](https://example.com)\n\n* one\n* two","score":120,"upvote_ratio":0.97,"num_comments":14,"over_18":true,"link_flair_text":"Discussion","domain":"self.boardgames","url":"https://www.reddit.com/r/boardgames/comments/self1/text_post/"}}]}}`,
+			"https://api.reddit.com/api/info?id=t3_link1": `{"data":{"children":[{"kind":"t3","data":{"id":"link1","title":"Link post","is_video":true,"domain":"v.redd.it","url":"https://v.redd.it/abc","preview":{"images":[{"source":{"url":"https://preview.redd.it/big.jpg?width=1280&amp;s=1","width":1280,"height":720},"resolutions":[{"url":"https://preview.redd.it/big.jpg?width=108&amp;s=2","width":108,"height":60},{"url":"https://preview.redd.it/big.jpg?width=640&amp;s=3","width":640,"height":360}]}]}}}]}}`,
+		}},
+	}
+
+	info, err := scraper.Scrape("https://www.reddit.com/r/boardgames/comments/self1/text_post/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectm.CheckJSON(info, &expectm.ExpectedM{
+		"Description":        "Hello & welcome\n\none\ntwo",
+		"Meta.Selftext":      "**Hello** &amp; [welcome](https://example.com)\n\n* one\n* two",
+		"Meta.Score":         float64(120),
+		"Meta.UpvoteRatio":   0.97,
+		"Meta.NumComments":   float64(14),
+		"Meta.Over18":        true,
+		"Meta.LinkFlairText": "Discussion",
+		"Meta.Domain":        "self.boardgames",
+	}); err != nil {
+		t.Error(err)
+	}
+
+	info, err = scraper.Scrape("https://www.reddit.com/r/videos/comments/link1/link_post/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectm.CheckJSON(info, &expectm.ExpectedM{
+		"Meta.IsVideo":        true,
+		"ThumbnailSources.0":  "https://preview.redd.it/big.jpg?width=1280&s=1",
+		"Thumbnails.0.Width":  float64(1280),
+		"Thumbnails.1.URL":    "https://preview.redd.it/big.jpg?width=640&s=3",
+		"Thumbnails.2.Height": float64(60),
+		"ThumbnailSources.2":  "https://preview.redd.it/big.jpg?width=108&s=2",
+	}); err != nil {
+		t.Error(err)
+	}
+	if len(info.ThumbnailSources) != len(info.Thumbnails) {
+		t.Errorf("Expected the same images in ThumbnailSources and Thumbnails but got %v and %v", info.ThumbnailSources, info.Thumbnails)
+	}
+}
+
+func TestRedditLegacyRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"method":%q,"agent":%q,"extra":%q,"payload":%q}`, r.Method, r.UserAgent(), r.Header.Get("X-Extra"), string(b))
+	}))
+	defer srv.Close()
+
+	rs := &RedditScraper{
+		UserAgent: "test-agent",
+		HTTPClient: &http.Client{Transport: fakeTransport{
+			"https://api.reddit.com/api/info?id=t3_jn78c5":  `{"data":{"children":[{"data":{"id":"jn78c5","title":"Fake Post"}}]}}`,
+			"https://api.reddit.com/api/info?id=t1_gb077ru": `{"data":{"children":[{"data":{"id":"gb077ru","author":"someone"}}]}}`,
+		}},
+	}
+
+	var body map[string]string
+	params := &request.Params{Url: srv.URL, Method: http.MethodPost, Headers: map[string]string{"X-Extra": "yes"}}
+	if err := rs.RedditRequest(params, map[string]string{"a": "b"}, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["method"] != http.MethodPost || body["agent"] != "test-agent" || body["extra"] != "yes" || body["payload"] != `{"a":"b"}` {
+		t.Errorf("Expected the params and payload to be sent but the server got %v", body)
+	}
+
+	post, err := rs.ScrapePost("jn78c5")
+	if err != nil || post.Title != "Fake Post" {
+		t.Errorf("Expected the post but got %v, %v", post, err)
+	}
+	comment, err := rs.ScrapeComment("gb077ru")
+	if err != nil || comment.CreditTitle != "someone" {
+		t.Errorf("Expected the comment but got %v, %v", comment, err)
+	}
+}
+
+// Counts the requests for each url on the way through to a fakeTransport
+type countingTransport struct {
+	fakeTransport
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	if c.requests == nil {
+		c.requests = make(map[string]int)
+	}
+	c.requests[req.URL.String()]++
+	c.mu.Unlock()
+	return c.fakeTransport.RoundTrip(req)
+}
+
+func TestRedditSubredditIdentity(t *testing.T) {
+	post := func(id, subreddit string) (string, string) {
+		return "https://api.reddit.com/api/info?id=t3_" + id, `{"data":{"children":[{"kind":"t3","data":{"id":"` + id + `","subreddit":"` + subreddit + `","subreddit_name_prefixed":"r/` + subreddit + `"}}]}}`
+	}
+	pages := fakeTransport{"https://api.reddit.com/r/boardgames/about": `{"data":{"community_icon":"https://styles.redditmedia.com/icon.png","primary_color":"#0079d3"}}`}
+	for _, p := range [][2]string{{"a", "boardgames"}, {"b", "boardgames"}, {"c", "secret"}, {"d", "secret"}} {
+		link, body := post(p[0], p[1])
+		pages[link] = body
+	}
+	transport := &countingTransport{fakeTransport: pages}
+	rs := &RedditScraper{HTTPClient: &http.Client{Transport: transport}}
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		info, err := rs.Scrape("https://www.reddit.com/comments/" + id + "/_/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		icon := "https://styles.redditmedia.com/icon.png"
+		if id == "c" || id == "d" {
+			icon = RedditIconURL
+		}
+		if info.SiteIconURL != icon {
+			t.Errorf("%s: expected the icon %s but got %s", id, icon, info.SiteIconURL)
+		}
+	}
+	// The missing subreddit isn't asked about again
+	for _, sub := range []string{"boardgames", "secret"} {
+		if n := transport.requests["https://api.reddit.com/r/"+sub+"/about"]; n != 1 {
+			t.Errorf("Expected r/%s to be looked up once but it was %d times", sub, n)
+		}
+	}
+
+	transport.requests = nil
+	rs = &RedditScraper{HTTPClient: &http.Client{Transport: transport}, SkipSubredditIdentity: true}
+	info, err := rs.Scrape("https://www.reddit.com/comments/a/_/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.SiteIconURL != RedditIconURL || info.SiteName != "r/boardgames" || len(transport.requests) != 1 {
+		t.Errorf("Expected reddit's icon without looking up the subreddit but got %+v after %v", info, transport.requests)
+	}
+}
@@ -0,0 +1,227 @@
+package vinscraper
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+)
+
+var (
+	ErrScraperNameTaken     = errors.New("a scraper is already registered with that name")
+	ErrScraperNotRegistered = errors.New("no scraper is registered with that name")
+)
+
+const (
+	PriorityDefault = 0
+)
+
+// Scrapers that want every url, like ScraperGeneric, return true so they're always tried last
+type CatchAllScraper interface {
+	CatchAll() bool
+}
+
+type RegisteredScraper struct {
+	Name     string
+	Priority int // Higher priorities are tried first
+	Enabled  bool
+	Scraper  Scraper
+
+	order int // Breaks ties between equal priorities, earlier registrations go first
+}
+
+func (r *RegisteredScraper) catchAll() bool {
+	return isCatchAll(r.Scraper)
+}
+
+func isCatchAll(scraper Scraper) bool {
+	ca, ok := scraper.(CatchAllScraper)
+	return ok && ca.CatchAll()
+}
+
+// Adds an enabled scraper under name
+func (s *Scraping) Register(name string, priority int, scraper Scraper) error {
+	s.registryMu.Lock()
+	defer s.registryMu.Unlock()
+	if s.findRegistered(name) != nil {
+		return ErrScraperNameTaken
+	}
+	s.registry = append(s.registry, &RegisteredScraper{
+		Name:     name,
+		Priority: priority,
+		Enabled:  true,
+		Scraper:  scraper,
+		order:    len(s.registry),
+	})
+	return nil
+}
+
+// Must be called with registryMu held
+func (s *Scraping) findRegistered(name string) *RegisteredScraper {
+	for _, r := range s.registry {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *Scraping) setEnabled(name string, enabled bool) error {
+	s.registryMu.Lock()
+	defer s.registryMu.Unlock()
+	r := s.findRegistered(name)
+	if r == nil {
+		return ErrScraperNotRegistered
+	}
+	r.Enabled = enabled
+	return nil
+}
+
+func (s *Scraping) Enable(name string) error {
+	return s.setEnabled(name, true)
+}
+
+func (s *Scraping) Disable(name string) error {
+	return s.setEnabled(name, false)
+}
+
+// Swaps the scraper registered under name, keeping its priority and whether it's enabled
+// It's swapped in Scrapers too if it's listed there, like the ones NewScraping adds
+func (s *Scraping) Replace(name string, scraper Scraper) error {
+	s.registryMu.Lock()
+	defer s.registryMu.Unlock()
+	r := s.findRegistered(name)
+	if r == nil {
+		return ErrScraperNotRegistered
+	}
+	for i, v := range s.Scrapers {
+		if sameScraper(v, r.Scraper) {
+			s.Scrapers[i] = scraper
+		}
+	}
+	r.Scraper = scraper
+	return nil
+}
+
+// Whether a and b are the same scraper, not just the same type of scraper
+func sameScraper(a, b Scraper) bool {
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
+
+// Every scraper in the order they're tried, disabled ones included
+// The ones in Scrapers go first in the order they're listed, then the registered ones by
+// priority, then the catch all scrapers from both
+// Scrapers that are also registered, like the ones NewScraping lists, are left to the registry
+func (s *Scraping) orderedScrapers() []RegisteredScraper {
+	s.registryMu.RLock()
+	registry := make([]RegisteredScraper, len(s.registry))
+	for i, r := range s.registry {
+		registry[i] = *r
+	}
+	s.registryMu.RUnlock()
+
+	var listed, listedLast []RegisteredScraper
+	shadowed := map[reflect.Type]bool{}
+Listed:
+	for _, v := range s.Scrapers {
+		for _, r := range registry {
+			if sameScraper(v, r.Scraper) {
+				continue Listed
+			}
+		}
+		r := RegisteredScraper{
+			Name:     ScraperName(v),
+			Priority: PriorityDefault,
+			Enabled:  true,
+			Scraper:  v,
+		}
+		if r.catchAll() {
+			listedLast = append(listedLast, r)
+		} else {
+			listed = append(listed, r)
+		}
+		shadowed[reflect.TypeOf(v)] = true
+	}
+
+	var first, last []RegisteredScraper
+	for _, r := range registry {
+		if shadowed[reflect.TypeOf(r.Scraper)] {
+			continue
+		}
+		if r.catchAll() {
+			last = append(last, r)
+		} else {
+			first = append(first, r)
+		}
+	}
+
+	byPriority := func(list []RegisteredScraper) {
+		sort.SliceStable(list, func(i, j int) bool {
+			if list[i].Priority != list[j].Priority {
+				return list[i].Priority > list[j].Priority
+			}
+			return list[i].order < list[j].order
+		})
+	}
+	byPriority(first)
+	byPriority(last)
+
+	ordered := append(listed, first...)
+	ordered = append(ordered, listedLast...)
+	return append(ordered, last...)
+}
+
+// The enabled scrapers in the order they're tried
+func (s *Scraping) EnabledScrapers() []RegisteredScraper {
+	all := s.orderedScrapers()
+	enabled := all[:0]
+	for _, r := range all {
+		if r.Enabled {
+			enabled = append(enabled, r)
+		}
+	}
+	return enabled
+}
+
+type ScraperDescription struct {
+	Name     string
+	Priority int
+	Enabled  bool
+	Wants    bool
+	Selected bool // This is the scraper that would handle the url
+	Reason   string
+}
+
+// Explains which scraper would handle link and why, without fetching anything
+func (s *Scraping) Describe(link string) []ScraperDescription {
+	all := s.orderedScrapers()
+	descriptions := make([]ScraperDescription, len(all))
+	selected := false
+	for i, r := range all {
+		d := ScraperDescription{
+			Name:     r.Name,
+			Priority: r.Priority,
+			Enabled:  r.Enabled,
+			Wants:    r.Scraper.WantsURL(link),
+		}
+		switch {
+		case !d.Enabled:
+			d.Reason = "disabled"
+		case !d.Wants:
+			d.Reason = "does not want this url"
+		case !selected:
+			selected = true
+			d.Selected = true
+			d.Reason = "first enabled scraper that wants this url"
+			if r.catchAll() {
+				d.Reason = "catch all, no other scraper wants this url"
+			}
+		case s.Fallback:
+			d.Reason = "wants this url, tried if the ones before it fail"
+		default:
+			d.Reason = "wants this url but an earlier scraper handles it"
+		}
+		descriptions[i] = d
+	}
+	return descriptions
+}
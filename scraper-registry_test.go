@@ -0,0 +1,160 @@
+package vinscraper
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestScraperRegistry(t *testing.T) {
+	scraping := NewScraping()
+	if err := scraping.Register("youtube", PriorityDefault, &YouTubeScraper{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := scraping.Register("reddit", PriorityDefault, &RedditScraper{}); err != ErrScraperNameTaken {
+		t.Errorf("Expected '%s' but got '%v'", ErrScraperNameTaken, err)
+	}
+
+	// The generic scraper was registered before youtube but still goes last
+	names := ""
+	for _, r := range scraping.EnabledScrapers() {
+		names += r.Name + " "
+	}
+	if names != "reddit youtube generic " {
+		t.Errorf("Scrapers are in the wrong order: %s", names)
+	}
+
+	describe := func(link string) string {
+		for _, d := range scraping.Describe(link) {
+			if d.Selected {
+				return d.Name
+			}
+		}
+		return ""
+	}
+	if name := describe("https://youtu.be/DP0t2MmOMEA"); name != "youtube" {
+		t.Errorf("Expected youtube to be selected but got '%s'", name)
+	}
+
+	if err := scraping.Disable("youtube"); err != nil {
+		t.Fatal(err)
+	}
+	if name := describe("https://youtu.be/DP0t2MmOMEA"); name != "generic" {
+		t.Errorf("Expected generic to be selected but got '%s'", name)
+	}
+	for _, d := range scraping.Describe("https://youtu.be/DP0t2MmOMEA") {
+		if d.Name == "youtube" && d.Reason != "disabled" {
+			t.Errorf("Expected youtube to be described as disabled but got '%s'", d.Reason)
+		}
+	}
+
+	if err := scraping.Enable("youtube"); err != nil {
+		t.Fatal(err)
+	}
+	if err := scraping.Replace("youtube", &staticScraper{ScrapeInfo{Title: "Replaced"}}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := scraping.Scrape("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "Replaced" || info.ScrapedBy != "youtube" {
+		t.Errorf("Expected the replacement scraper to run but got %+v", info)
+	}
+
+	if err := scraping.Disable("twitch"); err != ErrScraperNotRegistered {
+		t.Errorf("Expected '%s' but got '%v'", ErrScraperNotRegistered, err)
+	}
+}
+
+func TestScraperRegistryPriority(t *testing.T) {
+	scraping := &Scraping{}
+	scraping.Register("low", -1, &staticScraper{ScrapeInfo{Title: "low"}})
+	scraping.Register("high", 10, &staticScraper{ScrapeInfo{Title: "high"}})
+
+	info, err := scraping.Scrape("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ScrapedBy != "high" {
+		t.Errorf("Expected the high priority scraper to run but got '%s'", info.ScrapedBy)
+	}
+}
+
+func TestScraperRegistryLegacyScrapers(t *testing.T) {
+	pages := fakeTransport{
+		"https://api.reddit.com/api/info?id=t3_abc": `{"data":{"children":[{"kind":"t3","data":{"id":"abc","title":"From the listed scraper"}}]}}`,
+	}
+	scraping := NewScraping()
+	scraping.Scrapers = []Scraper{
+		&ScraperGeneric{},
+		&RedditScraper{UserAgent: "legacy", HTTPClient: &http.Client{Transport: pages}},
+		&YouTubeScraper{},
+	}
+
+	// The listed reddit and generic scrapers stand in for the registered ones and generic still goes last
+	names := ""
+	for _, r := range scraping.EnabledScrapers() {
+		names += r.Name + " "
+	}
+	if names != "RedditScraper YouTubeScraper ScraperGeneric " {
+		t.Errorf("Scrapers are in the wrong order: %s", names)
+	}
+
+	info, err := scraping.Scrape("https://www.reddit.com/comments/abc/_/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "From the listed scraper" || info.ScrapedBy != "RedditScraper" {
+		t.Errorf("Expected the listed reddit scraper to run but got %+v", info)
+	}
+}
+
+// Code written before the registry changed NewScraping().Scrapers directly
+func TestScraperRegistryNewScrapingScrapers(t *testing.T) {
+	scraping := NewScraping()
+	if len(scraping.Scrapers) != 2 {
+		t.Fatalf("Expected the 2 default scrapers in Scrapers but got %d", len(scraping.Scrapers))
+	}
+	if _, ok := scraping.Scrapers[0].(*RedditScraper); !ok {
+		t.Errorf("Expected the reddit scraper first but got %T", scraping.Scrapers[0])
+	}
+
+	// The listed defaults aren't tried twice
+	names := ""
+	for _, r := range scraping.EnabledScrapers() {
+		names += r.Name + " "
+	}
+	if names != "reddit generic " {
+		t.Errorf("Scrapers are in the wrong order: %s", names)
+	}
+
+	// Changing the listed reddit scraper changes the registered one
+	pages := fakeTransport{
+		"https://api.reddit.com/api/info?id=t3_abc": `{"data":{"children":[{"kind":"t3","data":{"id":"abc","title":"Configured"}}]}}`,
+	}
+	scraping.Scrapers[0].(*RedditScraper).HTTPClient = &http.Client{Transport: pages}
+	info, err := scraping.Scrape("https://www.reddit.com/comments/abc/_/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "Configured" || info.ScrapedBy != "reddit" {
+		t.Errorf("Expected the configured reddit scraper to run but got %+v", info)
+	}
+
+	// Scrapers put in front of the defaults run first
+	scraping.Scrapers = append([]Scraper{&staticScraper{ScrapeInfo{Title: "First"}}}, scraping.Scrapers...)
+	info, err = scraping.Scrape("https://www.reddit.com/comments/abc/_/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "First" {
+		t.Errorf("Expected the scraper in front to run but got %+v", info)
+	}
+
+	if err := scraping.Replace("reddit", &YouTubeScraper{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := scraping.Scrapers[1].(*YouTubeScraper); !ok {
+		t.Errorf("Expected Replace to swap the listed reddit scraper too but got %T", scraping.Scrapers[1])
+	}
+}
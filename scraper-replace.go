@@ -0,0 +1,121 @@
+package vinscraper
+
+import (
+	"errors"
+	"regexp"
+)
+
+var (
+	ErrReplacerNoField = errors.New("replacer needs a Field to replace in")
+)
+
+// Which part of the ScrapeInfo a ScrapeReplacer rewrites
+type ScrapeField string
+
+const (
+	FieldTitle       ScrapeField = "title"
+	FieldDescription ScrapeField = "description"
+	FieldCreditTitle ScrapeField = "credit_title"
+	FieldThumbnails  ScrapeField = "thumbnails" // Each thumbnail source is rewritten separately
+)
+
+// Compiles URLMatches and FindMatches so they don't need to be compiled for every scrape
+func (r *ScrapeReplacer) Compile() error {
+	var err error
+	if r.URLMatches != "" {
+		if r.urlRE, err = regexp.Compile(r.URLMatches); err != nil {
+			return err
+		}
+	}
+	r.findRE, err = regexp.Compile(r.FindMatches)
+	return err
+}
+
+// A compiled replacer, itself if AddReplacer or Compile already compiled it
+// Otherwise it was put in Replacers by hand and a compiled copy is made, leaving r alone
+// since other scrapes could be using it at the same time
+func (r *ScrapeReplacer) compiled() (*ScrapeReplacer, error) {
+	if r.findRE != nil {
+		return r, nil
+	}
+	c := *r
+	if err := c.Compile(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// True if this replacer should run on this result of scraping link
+// A URLMatches that doesn't compile matches nothing
+func (r *ScrapeReplacer) Matches(link string, info *ScrapeInfo) bool {
+	if r.SourceType != "" && r.SourceType != info.SourceType {
+		return false
+	}
+	c, err := r.compiled()
+	if err != nil {
+		return false
+	}
+	return c.urlRE == nil || c.urlRE.MatchString(link)
+}
+
+// Leaves s alone if FindMatches doesn't compile
+func (r *ScrapeReplacer) Replace(s string) string {
+	c, err := r.compiled()
+	if err != nil {
+		return s
+	}
+	return c.findRE.ReplaceAllString(s, c.ReplaceWith)
+}
+
+// Runs the replacer on its Field of info. The only error is a regex that doesn't compile
+func (r *ScrapeReplacer) Apply(link string, info *ScrapeInfo) error {
+	r, err := r.compiled()
+	if err != nil {
+		return err
+	}
+	if !r.Matches(link, info) {
+		return nil
+	}
+	switch r.Field {
+	case FieldTitle:
+		info.Title = r.Replace(info.Title)
+	case FieldDescription:
+		info.Description = r.Replace(info.Description)
+	case FieldCreditTitle:
+		info.CreditTitle = r.Replace(info.CreditTitle)
+	case FieldThumbnails:
+		for i, thumb := range info.ThumbnailSources {
+			info.ThumbnailSources[i] = r.Replace(thumb)
+		}
+		for i := range info.Thumbnails {
+			info.Thumbnails[i].URL = r.Replace(info.Thumbnails[i].URL)
+		}
+	}
+	return nil
+}
+
+// Compiles the replacer and adds it to the end of the pipeline
+// Replacers run in the order they're added, each one sees what the last one did
+func (s *Scraping) AddReplacer(r ScrapeReplacer) error {
+	if r.Field == "" {
+		return ErrReplacerNoField
+	}
+	if err := r.Compile(); err != nil {
+		return err
+	}
+	s.Replacers = append(s.Replacers, &r)
+	return nil
+}
+
+// Kept for TitleReplacers and DescriptionReplacers, which aren't compiled ahead of time
+func ScrapeReplace(link string, field *string, replaces []ScrapeReplacer) error {
+	for _, v := range replaces {
+		if err := v.Compile(); err != nil {
+			return err
+		}
+		if v.urlRE == nil || v.urlRE.MatchString(link) {
+			*field = v.Replace(*field)
+		}
+	}
+	return nil
+}
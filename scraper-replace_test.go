@@ -0,0 +1,92 @@
+package vinscraper
+
+import (
+	"testing"
+
+	"github.com/monstercat/golib/expectm"
+)
+
+// Always returns a copy of the same info
+type staticScraper struct {
+	info ScrapeInfo
+}
+
+func (s *staticScraper) WantsURL(link string) bool {
+	return true
+}
+
+func (s *staticScraper) Scrape(link string) (*ScrapeInfo, error) {
+	info := s.info
+	info.ThumbnailSources = append([]string{}, s.info.ThumbnailSources...)
+	return &info, nil
+}
+
+func TestScrapeReplacers(t *testing.T) {
+	scraping := &Scraping{
+		Scrapers: []Scraper{&staticScraper{ScrapeInfo{
+			CreditTitle:      "by someone",
+			Description:      "Price: $5.00 (sale)",
+			SourceType:       SourceURL,
+			ThumbnailSources: []string{"http://img.example.com/a_small.jpg", "http://img.example.com/b_small.jpg"},
+			Title:            "My Post | Example.com",
+		}}},
+	}
+
+	replacers := []ScrapeReplacer{
+		{Field: FieldTitle, URLMatches: `^https://example\.com/`, FindMatches: `\s*\|\s*Example\.com$`},
+		{Field: FieldDescription, FindMatches: `\$(\d+)\.(\d+)`, ReplaceWith: "$1 dollars"},
+		{Field: FieldCreditTitle, FindMatches: `^by `, SourceType: SourceURL},
+		{Field: FieldCreditTitle, FindMatches: `.*`, ReplaceWith: "never", SourceType: SourceRedditPost},
+		{Field: FieldThumbnails, FindMatches: `^http://(.*)_small\.jpg$`, ReplaceWith: "https://${1}_large.jpg"},
+	}
+	for _, r := range replacers {
+		if err := scraping.AddReplacer(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := scraping.Scrape("https://example.com/a?(weird)[url]*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectm.CheckJSON(info, &expectm.ExpectedM{
+		"Title":              "My Post",
+		"Description":        "Price: 5 dollars (sale)",
+		"CreditTitle":        "someone",
+		"ThumbnailSources.0": "https://img.example.com/a_large.jpg",
+		"ThumbnailSources.1": "https://img.example.com/b_large.jpg",
+		"Thumbnails.1.URL":   "https://img.example.com/b_large.jpg",
+	}); err != nil {
+		t.Error(err)
+	}
+
+	// The title replacer only wants example.com
+	info, err = scraping.Scrape("https://other.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "My Post | Example.com" {
+		t.Errorf("Title should not have been replaced but got '%s'", info.Title)
+	}
+
+	// Appended without AddReplacer, so never compiled
+	scraping.Replacers = append(scraping.Replacers, &ScrapeReplacer{Field: FieldTitle, FindMatches: `^My`, ReplaceWith: "Your"})
+	info, err = scraping.Scrape("https://other.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "Your Post | Example.com" {
+		t.Errorf("Expected the uncompiled replacer to run but got '%s'", info.Title)
+	}
+	scraping.Replacers = append(scraping.Replacers, &ScrapeReplacer{Field: FieldTitle, FindMatches: "("})
+	if _, err := scraping.Scrape("https://other.com/"); err == nil {
+		t.Error("Expected a bad regex appended to Replacers to fail the scrape")
+	}
+
+	if err := scraping.AddReplacer(ScrapeReplacer{FindMatches: "x"}); err != ErrReplacerNoField {
+		t.Errorf("Expected '%s' but got '%v'", ErrReplacerNoField, err)
+	}
+	if err := scraping.AddReplacer(ScrapeReplacer{Field: FieldTitle, FindMatches: "("}); err == nil {
+		t.Error("Expected a bad regex to fail to compile")
+	}
+}
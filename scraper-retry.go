@@ -0,0 +1,120 @@
+package vinscraper
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultRetryAttempts  = 3
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+	DefaultRetryMaxDelay  = 30 * time.Second
+)
+
+// How Scraping retries scrapes that failed with a retryable ScrapeError
+// Waits grow exponentially with jitter, unless the server said how long to wait
+type RetryPolicy struct {
+	MaxAttempts int           // Including the first attempt
+	BaseDelay   time.Duration // Wait before the first retry, doubled for each one after
+	// Cap on the exponential wait. When the server asks for a longer wait than this,
+	// like a Twitter limit that resets in 15 minutes, the scrape gives up instead
+	MaxDelay time.Duration
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return DefaultRetryAttempts
+}
+
+func (p *RetryPolicy) maxDelay() time.Duration {
+	if p.MaxDelay > 0 {
+		return p.MaxDelay
+	}
+	return DefaultRetryMaxDelay
+}
+
+// How long to wait before retry number attempt (starting at 1)
+// False if the server asked for a longer wait than MaxDelay and it's not worth retrying
+func (p *RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	max := p.maxDelay()
+	var se *ScrapeError
+	if errors.As(err, &se) && se.RetryAfter > 0 {
+		return se.RetryAfter, se.RetryAfter <= max
+	}
+
+	base := p.BaseDelay
+	if base <= 0 {
+		base = DefaultRetryBaseDelay
+	}
+
+	d := base << uint(attempt-1)
+	if d > max || d <= 0 {
+		d = max
+	}
+	// Somewhere between half and all of it so a batch of failures doesn't retry in lockstep
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1)), true
+}
+
+// Scrapes with the policy, giving up early if the wait would run past the context's deadline or MaxDelay
+func (p *RetryPolicy) scrape(ctx context.Context, s Scraper, link string) (*ScrapeInfo, error) {
+	for attempt := 1; ; attempt++ {
+		info, err := scrapeContext(ctx, s, link)
+		if err == nil || !IsRetryable(err) || attempt >= p.maxAttempts() {
+			return info, err
+		}
+
+		wait, ok := p.delay(attempt, err)
+		if !ok {
+			return nil, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return nil, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Works out how long a response says to wait before trying again
+// Retry-After is standard, Twitter sends the unix time its limit resets at and
+// Reddit sends the number of seconds until its limit resets
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	if v := header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+		if at, err := http.ParseTime(v); err == nil {
+			return positive(at.Sub(now))
+		}
+	}
+	if header.Get("X-Rate-Limit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-Rate-Limit-Reset"), 10, 64); err == nil {
+			return positive(time.Unix(reset, 0).Sub(now))
+		}
+	}
+	if remaining, err := strconv.ParseFloat(header.Get("X-Ratelimit-Remaining"), 64); err == nil && remaining < 1 {
+		if secs, err := strconv.ParseFloat(header.Get("X-Ratelimit-Reset"), 64); err == nil {
+			return positive(time.Duration(secs * float64(time.Second)))
+		}
+	}
+	return 0
+}
+
+func positive(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,106 @@
+package vinscraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Fails with err until it's been called failures times
+type flakyScraper struct {
+	mu       sync.Mutex
+	calls    int
+	failures int
+	err      error
+}
+
+func (f *flakyScraper) WantsURL(link string) bool {
+	return true
+}
+
+func (f *flakyScraper) Scrape(link string) (*ScrapeInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, f.err
+	}
+	return &ScrapeInfo{Title: "Finally"}, nil
+}
+
+func TestScrapingRetry(t *testing.T) {
+	transient := newScrapeError("test", ErrorKindTransient, errors.New("connection reset"))
+	flaky := &flakyScraper{failures: 2, err: transient}
+	scraping := &Scraping{
+		Retry:    &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+		Scrapers: []Scraper{flaky},
+	}
+	info, err := scraping.Scrape("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "Finally" || flaky.calls != 3 {
+		t.Errorf("Expected to succeed on the third call but got %+v after %d calls", info, flaky.calls)
+	}
+
+	notFound := newScrapeError("test", ErrorKindNotFound, errors.New("gone"))
+	flaky = &flakyScraper{failures: 2, err: notFound}
+	scraping.Scrapers = []Scraper{flaky}
+	if _, err := scraping.Scrape("https://example.com/"); err != notFound || flaky.calls != 1 {
+		t.Errorf("Expected not found to fail without retrying but got '%v' after %d calls", err, flaky.calls)
+	}
+}
+
+func TestScrapingRetryDeadline(t *testing.T) {
+	limited := newScrapeError("test", ErrorKindRateLimited, errors.New("slow down"))
+	limited.RetryAfter = time.Minute
+	flaky := &flakyScraper{failures: 1, err: limited}
+	scraping := &Scraping{
+		Retry:    &RetryPolicy{},
+		Scrapers: []Scraper{flaky},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := scraping.ScrapeContext(ctx, "https://example.com/"); err != limited {
+		t.Errorf("Expected '%s' but got '%v'", limited, err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("Should have given up instead of waiting past the deadline")
+	}
+
+	// Without a deadline it still won't wait longer than MaxDelay
+	limited.RetryAfter = 15 * time.Minute
+	flaky = &flakyScraper{failures: 1, err: limited}
+	scraping.Scrapers = []Scraper{flaky}
+	start = time.Now()
+	if _, err := scraping.Scrape("https://example.com/"); err != limited || flaky.calls != 1 {
+		t.Errorf("Expected '%s' without a retry but got '%v' after %d calls", limited, err, flaky.calls)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("Should have given up instead of waiting past MaxDelay")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	tests := []struct {
+		header   http.Header
+		expected time.Duration
+	}{
+		{http.Header{"Retry-After": {"120"}}, 2 * time.Minute},
+		{http.Header{"Retry-After": {now.Add(time.Minute).UTC().Format(http.TimeFormat)}}, time.Minute},
+		{http.Header{"X-Rate-Limit-Remaining": {"0"}, "X-Rate-Limit-Reset": {"1600000030"}}, 30 * time.Second},
+		{http.Header{"X-Ratelimit-Remaining": {"0.0"}, "X-Ratelimit-Reset": {"45"}}, 45 * time.Second},
+		{http.Header{"X-Ratelimit-Remaining": {"12"}, "X-Ratelimit-Reset": {"45"}}, 0},
+	}
+	for i, test := range tests {
+		if d := retryAfter(test.header, now); d != test.expected {
+			t.Errorf("[%d] Expected %s but got %s", i, test.expected, d)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package vinscraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrUnsafeURL = errors.New("refusing to fetch unsafe url")
+)
+
+// Addresses a user submitted link should never be able to make us fetch
+var unsafeNetworks = parseCIDRs(
+	"0.0.0.0/8",      // "This" network
+	"10.0.0.0/8",     // Private
+	"100.64.0.0/10",  // Carrier grade NAT
+	"127.0.0.0/8",    // Loopback
+	"169.254.0.0/16", // Link local, includes the 169.254.169.254 cloud metadata address
+	"172.16.0.0/12",  // Private
+	"192.0.0.0/24",   // IETF protocol assignments
+	"192.168.0.0/16", // Private
+	"198.18.0.0/15",  // Benchmarking
+	"224.0.0.0/4",    // Multicast
+	"240.0.0.0/4",    // Reserved, includes broadcast
+	"::/128",         // Unspecified
+	"::1/128",        // Loopback
+	"64:ff9b::/96",   // IPv4 translation, could point at anything above
+	"fc00::/7",       // Unique local, includes the fd00:ec2::254 metadata address
+	"fe80::/10",      // Link local
+	"ff00::/8",       // Multicast
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+func isUnsafeIP(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, n := range unsafeNetworks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func unsafeURLError(format string, args ...interface{}) error {
+	err := fmt.Errorf("%w: %s", ErrUnsafeURL, fmt.Sprintf(format, args...))
+	return newScrapeError(SourceNameGeneric, ErrorKindBlocked, err)
+}
+
+// Only http and https on their usual ports, or ports in allowedPorts
+func checkSafeURL(u *url.URL, allowedPorts []int) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return unsafeURLError("scheme %q is not allowed", u.Scheme)
+	}
+	port := u.Port()
+	if port == "" || port == "80" || port == "443" {
+		return nil
+	}
+	p, err := strconv.Atoi(port)
+	if err == nil {
+		for _, allowed := range allowedPorts {
+			if p == allowed {
+				return nil
+			}
+		}
+	}
+	return unsafeURLError("port %s is not allowed", port)
+}
+
+// Runs on every connection after DNS has been resolved, so a hostname that resolves
+// to a private address is caught no matter how we got to it
+func safeDialControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isUnsafeIP(ip) {
+		return unsafeURLError("%s is not a public address", host)
+	}
+	return nil
+}
+
+// Checks every request the client sends, redirects included
+type safeTransport struct {
+	base         http.RoundTripper
+	allowedPorts []int
+	// False when base dials with safeDialControl, otherwise we look the host up ourselves
+	resolve bool
+}
+
+func (t *safeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := checkSafeURL(req.URL, t.allowedPorts); err != nil {
+		return nil, err
+	}
+	if t.resolve {
+		if err := checkSafeHost(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+func checkSafeHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if isUnsafeIP(ip) {
+			return unsafeURLError("%s is not a public address", host)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if isUnsafeIP(addr.IP) {
+			return unsafeURLError("%s resolves to %s which is not a public address", host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// Returns a copy of client that refuses to connect anywhere unsafe
+// When the client uses an *http.Transport the check happens at dial time, after DNS,
+// which also stops DNS rebinding. The transport's proxy is dropped since the proxy would
+// resolve the host where we can't check it. Other transports, like test doubles, get the
+// host looked up and checked before each request instead.
+func safeClient(client *http.Client, allowedPorts []int) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	safe := &safeTransport{
+		base:         base,
+		allowedPorts: allowedPorts,
+		resolve:      true,
+	}
+	if tr, ok := base.(*http.Transport); ok {
+		tr = tr.Clone()
+		tr.Proxy = nil
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   safeDialControl,
+		}
+		tr.DialContext = dialer.DialContext
+		tr.DialTLSContext = nil
+		safe.base = tr
+		safe.resolve = false
+	}
+
+	c := *client
+	c.Transport = safe
+	return &c
+}
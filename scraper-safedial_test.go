@@ -0,0 +1,56 @@
+package vinscraper
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestScrapeGenericSafeMode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Internal</title></head></html>`))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	tests := []struct {
+		scraper *ScraperGeneric
+		link    string
+	}{
+		// Blocked when dialing, even with the port allowed
+		{&ScraperGeneric{SafeMode: true, AllowedPorts: []int{port}}, server.URL},
+		{&ScraperGeneric{SafeMode: true}, server.URL},
+		{&ScraperGeneric{SafeMode: true}, "http://169.254.169.254/latest/meta-data/"},
+		{&ScraperGeneric{SafeMode: true}, "http://93.184.216.34:6379/"},
+		{&ScraperGeneric{SafeMode: true}, "ftp://example.com/file"},
+		// Every redirect is checked too
+		{&ScraperGeneric{
+			SafeMode:   true,
+			HTTPClient: &http.Client{Transport: redirectingTransport{redirects: map[string]string{"http://93.184.216.34/": "http://localhost:6379/"}}},
+		}, "http://93.184.216.34/"},
+		{&ScraperGeneric{
+			SafeMode:   true,
+			HTTPClient: &http.Client{Transport: redirectingTransport{redirects: map[string]string{"http://93.184.216.34/": "http://[::1]/"}}},
+		}, "http://93.184.216.34/"},
+	}
+
+	for i, test := range tests {
+		_, err := test.scraper.Scrape(test.link)
+		if !errors.Is(err, ErrUnsafeURL) || ErrorKindOf(err) != ErrorKindBlocked {
+			t.Errorf("[%d] Expected %s to be blocked but got '%v'", i, test.link, err)
+		}
+	}
+
+	// Without safe mode it's fetched like anything else
+	info, err := (&ScraperGeneric{}).Scrape(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "Internal" {
+		t.Errorf("Expected the page to be scraped but got %+v", info)
+	}
+}
@@ -0,0 +1,415 @@
+package vinscraper
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// The schema.org types the generic scraper understands
+var SchemaTypes = []string{
+	"Article",
+	"NewsArticle",
+	"VideoObject",
+	"Product",
+	"Recipe",
+	"Event",
+	"Person",
+}
+
+type SchemaPerson struct {
+	Name string
+	URL  string
+}
+
+// A schema.org object from a page's JSON-LD or microdata
+// Type says which it is, fields that don't apply to that type are left empty
+type SchemaObject struct {
+	Type          string
+	Name          string // Headline for articles
+	Description   string
+	URL           string
+	Images        []string
+	Authors       []SchemaPerson
+	DatePublished string
+	DateModified  string
+
+	// VideoObject
+	Duration   string
+	UploadDate string
+	ContentURL string
+	EmbedURL   string
+
+	// Product
+	Brand         string
+	SKU           string
+	Price         string
+	PriceCurrency string
+	Availability  string
+
+	// Recipe
+	Ingredients []string
+	Yield       string
+	PrepTime    string
+	CookTime    string
+	TotalTime   string
+
+	// Event
+	StartDate string
+	EndDate   string
+	Location  string
+
+	// Person
+	JobTitle string
+	SameAs   []string
+}
+
+// Finds the first object of one of the SchemaTypes on the page, JSON-LD first then microdata
+// Returns nil if there isn't one
+func FindSchemaObject(doc *html.Node) *SchemaObject {
+	for _, item := range append(jsonLDItems(doc), microdataItems(doc)...) {
+		if obj := schemaObject(item); obj != nil {
+			return obj
+		}
+	}
+	return nil
+}
+
+// Both JSON-LD and microdata come out of here as the maps and slices encoding/json makes
+// so the same code can read either of them
+func schemaObject(item map[string]interface{}) *SchemaObject {
+	typ := schemaType(item["@type"])
+	if typ == "" {
+		return nil
+	}
+
+	obj := &SchemaObject{
+		Type:          typ,
+		Name:          schemaString(item["name"]),
+		Description:   schemaString(item["description"]),
+		URL:           schemaString(item["url"]),
+		Images:        schemaURLs(item["image"]),
+		Authors:       schemaPeople(item["author"]),
+		DatePublished: schemaString(item["datePublished"]),
+		DateModified:  schemaString(item["dateModified"]),
+	}
+	if headline := schemaString(item["headline"]); headline != "" {
+		obj.Name = headline
+	}
+	if len(obj.Authors) == 0 {
+		obj.Authors = schemaPeople(item["creator"])
+	}
+
+	switch typ {
+	case "VideoObject":
+		obj.Duration = schemaString(item["duration"])
+		obj.UploadDate = schemaString(item["uploadDate"])
+		obj.ContentURL = schemaString(item["contentUrl"])
+		obj.EmbedURL = schemaString(item["embedUrl"])
+		if len(obj.Images) == 0 {
+			obj.Images = schemaURLs(item["thumbnailUrl"])
+		}
+	case "Product":
+		obj.Brand = schemaString(item["brand"])
+		obj.SKU = schemaString(item["sku"])
+		if offers := schemaMaps(item["offers"]); len(offers) > 0 {
+			obj.Price = schemaString(offers[0]["price"])
+			if obj.Price == "" {
+				obj.Price = schemaString(offers[0]["lowPrice"])
+			}
+			obj.PriceCurrency = schemaString(offers[0]["priceCurrency"])
+			obj.Availability = schemaString(offers[0]["availability"])
+		}
+	case "Recipe":
+		obj.Ingredients = schemaStrings(item["recipeIngredient"])
+		if len(obj.Ingredients) == 0 {
+			obj.Ingredients = schemaStrings(item["ingredients"])
+		}
+		obj.Yield = schemaString(item["recipeYield"])
+		obj.PrepTime = schemaString(item["prepTime"])
+		obj.CookTime = schemaString(item["cookTime"])
+		obj.TotalTime = schemaString(item["totalTime"])
+	case "Event":
+		obj.StartDate = schemaString(item["startDate"])
+		obj.EndDate = schemaString(item["endDate"])
+		obj.Location = schemaString(item["location"])
+	case "Person":
+		obj.JobTitle = schemaString(item["jobTitle"])
+		obj.SameAs = schemaURLs(item["sameAs"])
+	}
+	return obj
+}
+
+// @type can be a name, a full schema.org url or a list of either
+func schemaType(v interface{}) string {
+	for _, t := range schemaStrings(v) {
+		t = t[strings.LastIndex(t, "/")+1:]
+		for _, known := range SchemaTypes {
+			if t == known {
+				return t
+			}
+		}
+	}
+	return ""
+}
+
+// Most properties can be text, a list or an object with a name
+func schemaString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return normalizeText(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		if len(v) > 0 {
+			return schemaString(v[0])
+		}
+	case map[string]interface{}:
+		for _, key := range []string{"@value", "name", "text", "address", "streetAddress"} {
+			if s := schemaString(v[key]); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func schemaStrings(v interface{}) []string {
+	var list []string
+	if items, ok := v.([]interface{}); ok {
+		for _, item := range items {
+			if s := schemaString(item); s != "" {
+				list = append(list, s)
+			}
+		}
+	} else if s := schemaString(v); s != "" {
+		list = append(list, s)
+	}
+	return list
+}
+
+// Images and the like are a url, an object with one or a list of either
+func schemaURLs(v interface{}) []string {
+	var list []string
+	switch v := v.(type) {
+	case string:
+		if v != "" {
+			list = append(list, strings.TrimSpace(v))
+		}
+	case []interface{}:
+		for _, item := range v {
+			list = append(list, schemaURLs(item)...)
+		}
+	case map[string]interface{}:
+		for _, key := range []string{"url", "contentUrl", "@id"} {
+			if s, ok := v[key].(string); ok && s != "" {
+				list = append(list, strings.TrimSpace(s))
+				break
+			}
+		}
+	}
+	return list
+}
+
+func schemaMaps(v interface{}) []map[string]interface{} {
+	var list []map[string]interface{}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		list = append(list, v)
+	case []interface{}:
+		for _, item := range v {
+			list = append(list, schemaMaps(item)...)
+		}
+	}
+	return list
+}
+
+func schemaPeople(v interface{}) []SchemaPerson {
+	var people []SchemaPerson
+	switch v := v.(type) {
+	case string:
+		if name := normalizeText(v); name != "" {
+			people = append(people, SchemaPerson{Name: name})
+		}
+	case []interface{}:
+		for _, item := range v {
+			people = append(people, schemaPeople(item)...)
+		}
+	case map[string]interface{}:
+		person := SchemaPerson{Name: schemaString(v["name"])}
+		if urls := schemaURLs(v["url"]); len(urls) > 0 {
+			person.URL = urls[0]
+		}
+		if person.Name != "" {
+			people = append(people, person)
+		}
+	}
+	return people
+}
+
+// Every object in the page's ld+json scripts, including the ones in an @graph
+func jsonLDItems(doc *html.Node) []map[string]interface{} {
+	var items []map[string]interface{}
+	var add func(v interface{})
+	add = func(v interface{}) {
+		switch v := v.(type) {
+		case []interface{}:
+			for _, item := range v {
+				add(item)
+			}
+		case map[string]interface{}:
+			if graph, ok := v["@graph"]; ok {
+				add(graph)
+				return
+			}
+			items = append(items, v)
+		}
+	}
+
+	walkHTML(doc, func(n *html.Node) bool {
+		if n.Data != "script" || !strings.EqualFold(strings.TrimSpace(htmlAttr(n, "type")), "application/ld+json") {
+			return true
+		}
+		var v interface{}
+		// Broken JSON-LD is common enough that it's not worth failing the scrape over
+		if err := json.Unmarshal([]byte(htmlText(n)), &v); err == nil {
+			add(unescapeJSONStrings(v))
+		}
+		return false
+	})
+	return items
+}
+
+// The parser leaves script contents alone, but sites escape the text in their JSON-LD
+// like any other text on the page, so it gets decoded here instead
+func unescapeJSONStrings(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return html.UnescapeString(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = unescapeJSONStrings(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = unescapeJSONStrings(v[key])
+		}
+	}
+	return v
+}
+
+// The page's top level microdata items, turned into the same shape JSON-LD has
+func microdataItems(doc *html.Node) []map[string]interface{} {
+	var items []map[string]interface{}
+	walkHTML(doc, func(n *html.Node) bool {
+		if !hasHTMLAttr(n, "itemscope") {
+			return true
+		}
+		if !hasHTMLAttr(n, "itemprop") {
+			items = append(items, microdataItem(n))
+		}
+		return false
+	})
+	return items
+}
+
+func microdataItem(scope *html.Node) map[string]interface{} {
+	var types []interface{}
+	for _, t := range strings.Fields(htmlAttr(scope, "itemtype")) {
+		types = append(types, t)
+	}
+	item := map[string]interface{}{
+		"@type": types,
+	}
+
+	for c := scope.FirstChild; c != nil; c = c.NextSibling {
+		walkHTML(c, func(n *html.Node) bool {
+			names := strings.Fields(htmlAttr(n, "itemprop"))
+			if len(names) > 0 {
+				value := microdataValue(n)
+				for _, name := range names {
+					switch existing := item[name].(type) {
+					case nil:
+						item[name] = value
+					case []interface{}:
+						item[name] = append(existing, value)
+					default:
+						item[name] = []interface{}{existing, value}
+					}
+				}
+			}
+			// Properties inside a nested item belong to it, not us
+			return !hasHTMLAttr(n, "itemscope")
+		})
+	}
+	return item
+}
+
+// https://html.spec.whatwg.org/multipage/microdata.html#values
+func microdataValue(n *html.Node) interface{} {
+	if hasHTMLAttr(n, "itemscope") {
+		return microdataItem(n)
+	}
+	switch n.Data {
+	case "meta":
+		return htmlAttr(n, "content")
+	case "a", "area", "link":
+		return htmlAttr(n, "href")
+	case "img", "audio", "video", "source", "iframe", "embed", "track":
+		return htmlAttr(n, "src")
+	case "object":
+		return htmlAttr(n, "data")
+	case "data", "meter":
+		return htmlAttr(n, "value")
+	case "time":
+		if hasHTMLAttr(n, "datetime") {
+			return htmlAttr(n, "datetime")
+		}
+	}
+	return htmlText(n)
+}
+
+// Calls fn on every element under n. Returning false skips that element's children
+func walkHTML(n *html.Node, fn func(*html.Node) bool) {
+	if n.Type == html.ElementNode && !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkHTML(c, fn)
+	}
+}
+
+func hasHTMLAttr(n *html.Node, key string) bool {
+	for _, attr := range n.Attr {
+		if strings.EqualFold(attr.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if strings.EqualFold(attr.Key, key) {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func htmlText(n *html.Node) string {
+	var b strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package vinscraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/monstercat/golib/expectm"
+	"golang.org/x/net/html"
+)
+
+func findSchema(t *testing.T, page string) *SchemaObject {
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return FindSchemaObject(doc)
+}
+
+func TestFindSchemaObjectJSONLD(t *testing.T) {
+	obj := findSchema(t, `<html><head>
+<script type="application/ld+json">{not json</script>
+<script type="application/ld+json">
+{
+	"@context": "https://schema.org",
+	"@graph": [
+		{"@type": "WebSite", "name": "The Daily Meeple"},
+		{
+			"@type": ["NewsArticle"],
+			"headline": "Gloomhaven  sequel &amp; more",
+			"description": "A new campaign",
+			"image": [{"@type": "ImageObject", "url": "https://example.com/a.jpg"}, "https://example.com/b.jpg"],
+			"author": [{"@type": "Person", "name": "Isaac Childres", "url": "https://example.com/isaac"}, "Someone Else"],
+			"datePublished": "2020-11-10"
+		}
+	]
+}
+</script></head></html>`)
+
+	if err := expectm.CheckJSON(obj, &expectm.ExpectedM{
+		"Type":           "NewsArticle",
+		"Name":           "Gloomhaven sequel & more",
+		"Description":    "A new campaign",
+		"Images.0":       "https://example.com/a.jpg",
+		"Images.1":       "https://example.com/b.jpg",
+		"Authors.0.Name": "Isaac Childres",
+		"Authors.0.URL":  "https://example.com/isaac",
+		"Authors.1.Name": "Someone Else",
+		"DatePublished":  "2020-11-10",
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFindSchemaObjectMicrodata(t *testing.T) {
+	obj := findSchema(t, `<html><body>
+<div itemscope itemtype="https://schema.org/Product">
+	<h1 itemprop="name">Wingspan</h1>
+	<img itemprop="image" src="https://example.com/wingspan.jpg">
+	<p itemprop="description">A bird  themed
+		engine builder</p>
+	<div itemprop="brand" itemscope itemtype="https://schema.org/Brand"><span itemprop="name">Stonemaier</span></div>
+	<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+		<meta itemprop="priceCurrency" content="USD">
+		<span itemprop="price">55.00</span>
+		<link itemprop="availability" href="https://schema.org/InStock">
+	</div>
+</div>
+</body></html>`)
+
+	if err := expectm.CheckJSON(obj, &expectm.ExpectedM{
+		"Type":          "Product",
+		"Name":          "Wingspan",
+		"Description":   "A bird themed engine builder",
+		"Images.0":      "https://example.com/wingspan.jpg",
+		"Brand":         "Stonemaier",
+		"Price":         "55.00",
+		"PriceCurrency": "USD",
+		"Availability":  "https://schema.org/InStock",
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFindSchemaObjectRecipe(t *testing.T) {
+	obj := findSchema(t, `<script type="application/ld+json">
+{"@type": "http://schema.org/Recipe", "name": "Pancakes", "recipeIngredient": ["Flour", "Milk", "Eggs"], "recipeYield": 4, "totalTime": "PT20M"}
+</script>`)
+
+	if err := expectm.CheckJSON(obj, &expectm.ExpectedM{
+		"Type":          "Recipe",
+		"Name":          "Pancakes",
+		"Ingredients.2": "Eggs",
+		"Yield":         "4",
+		"TotalTime":     "PT20M",
+	}); err != nil {
+		t.Error(err)
+	}
+
+	if obj := findSchema(t, `<script type="application/ld+json">{"@type": "WebPage", "name": "Home"}</script>`); obj != nil {
+		t.Errorf("Expected no object for an unsupported type but got %+v", obj)
+	}
+}
+
+func TestScrapeGenericSchema(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Pancakes | Recipe Site</title>
+<script type="application/ld+json">
+{"@type": "Recipe", "name": "Pancakes", "description": "Fluffy ones", "image": "https://example.com/pancakes.jpg",
+ "author": {"@type": "Person", "name": "Chef", "url": "https://example.com/chef"}}
+</script></head></html>`))
+	}))
+	defer server.Close()
+
+	info, err := (&ScraperGeneric{}).Scrape(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectm.CheckJSON(info, &expectm.ExpectedM{
+		"Title":              "Pancakes",
+		"Description":        "Fluffy ones",
+		"CreditTitle":        "Chef",
+		"CreditURL":          "https://example.com/chef",
+		"ThumbnailSources.0": "https://example.com/pancakes.jpg",
+		"Meta.Schema.Type":   "Recipe",
+	}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,201 @@
+package vinscraper
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Manifests are small, anything bigger than this isn't one
+const maxManifestBytes = 1 << 20
+
+// https://developer.mozilla.org/en-US/docs/Web/Manifest
+type WebAppManifest struct {
+	Name       string `json:"name"`
+	ShortName  string `json:"short_name"`
+	ThemeColor string `json:"theme_color"`
+	Icons      []struct {
+		Src     string `json:"src"`
+		Sizes   string `json:"sizes"`
+		Purpose string `json:"purpose"`
+	} `json:"icons"`
+}
+
+type siteIcon struct {
+	URL  string
+	Size int // Width in pixels, 0 if the page didn't say
+}
+
+// What a page says about the site it's on
+type siteIdentity struct {
+	Icons       []siteIcon
+	ManifestURL string
+	ThemeColor  string
+	AppName     string
+}
+
+// Reads the icon, manifest and theme color tags, with urls resolved against base
+// or the page's <base href> if it has one
+func parseSiteIdentity(doc *html.Node, base *url.URL) *siteIdentity {
+	site := &siteIdentity{}
+	walkHTML(doc, func(n *html.Node) bool {
+		switch n.Data {
+		case "base":
+			if href, err := url.Parse(strings.TrimSpace(htmlAttr(n, "href"))); err == nil && htmlAttr(n, "href") != "" {
+				base = base.ResolveReference(href)
+			}
+		case "meta":
+			switch strings.ToLower(htmlAttr(n, "name")) {
+			case "theme-color":
+				// The first one is the light mode color when there's one per color scheme
+				if site.ThemeColor == "" {
+					site.ThemeColor = strings.TrimSpace(htmlAttr(n, "content"))
+				}
+			case "application-name":
+				site.AppName = normalizeText(htmlAttr(n, "content"))
+			}
+		case "link":
+			href := resolveURL(base, htmlAttr(n, "href"))
+			if href == "" {
+				return false
+			}
+			for _, rel := range strings.Fields(strings.ToLower(htmlAttr(n, "rel"))) {
+				switch rel {
+				case "icon":
+					site.Icons = append(site.Icons, siteIcon{href, iconSize(htmlAttr(n, "sizes"))})
+				case "apple-touch-icon", "apple-touch-icon-precomposed":
+					size := iconSize(htmlAttr(n, "sizes"))
+					if size == 0 {
+						size = 180 // What iOS asks for when there's no size
+					}
+					site.Icons = append(site.Icons, siteIcon{href, size})
+				case "manifest":
+					site.ManifestURL = href
+				}
+			}
+		}
+		return true
+	})
+	return site
+}
+
+// Adds the manifest's icons. Maskable ones are meant to be cropped so they're skipped
+func (site *siteIdentity) addManifest(manifest *WebAppManifest, manifestURL *url.URL) {
+	for _, icon := range manifest.Icons {
+		if icon.Purpose != "" && !strings.Contains(icon.Purpose, "any") {
+			continue
+		}
+		if src := resolveURL(manifestURL, icon.Src); src != "" {
+			site.Icons = append(site.Icons, siteIcon{src, iconSize(icon.Sizes)})
+		}
+	}
+	if site.ThemeColor == "" {
+		site.ThemeColor = manifest.ThemeColor
+	}
+}
+
+// The biggest icon, or /favicon.ico if the page didn't list any
+func (site *siteIdentity) bestIcon(base *url.URL) string {
+	best := -1
+	for i, icon := range site.Icons {
+		if best < 0 || icon.Size > site.Icons[best].Size {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return site.Icons[best].URL
+	}
+	return resolveURL(base, "/favicon.ico")
+}
+
+// sizes is like "16x16 32x32" or "any" for svgs, which scale to anything
+func iconSize(sizes string) int {
+	largest := 0
+	for _, size := range strings.Fields(strings.ToLower(sizes)) {
+		if size == "any" {
+			return 1 << 16
+		}
+		width, _ := strconv.Atoi(strings.SplitN(size, "x", 2)[0])
+		if width > largest {
+			largest = width
+		}
+	}
+	return largest
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+func fetchManifest(ctx context.Context, client *http.Client, link string) (*WebAppManifest, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, httpResponseError(SourceNameGeneric, resp, ErrBadStatus)
+	}
+
+	var manifest WebAppManifest
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestBytes)).Decode(&manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// Fills in SiteName, SiteIconURL and ThemeColor for a page at base
+func (s *ScraperGeneric) scrapeSiteIdentity(ctx context.Context, client *http.Client, doc *html.Node, base *url.URL, item *ScrapeInfo, siteName string) {
+	site := parseSiteIdentity(doc, base)
+
+	var manifest *WebAppManifest
+	if site.ManifestURL != "" && !s.SkipManifest {
+		// A missing or broken manifest just means fewer icons to pick from
+		if m, err := fetchManifest(ctx, client, site.ManifestURL); err == nil {
+			manifest = m
+			manifestURL, _ := url.Parse(site.ManifestURL)
+			site.addManifest(manifest, manifestURL)
+		}
+	}
+
+	if siteName == "" && manifest != nil {
+		siteName = normalizeText(manifest.Name)
+		if siteName == "" {
+			siteName = normalizeText(manifest.ShortName)
+		}
+	}
+	if siteName == "" {
+		siteName = site.AppName
+	}
+	if siteName == "" {
+		siteName = strings.TrimPrefix(base.Hostname(), "www.")
+	}
+
+	item.SiteName = siteName
+	item.SiteIconURL = site.bestIcon(base)
+	item.ThemeColor = site.ThemeColor
+}
@@ -0,0 +1,85 @@
+package vinscraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/monstercat/golib/expectm"
+)
+
+func TestScrapeGenericSiteIdentity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/games/catan", http.StatusFound)
+		case "/games/catan":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Catan</title>
+<link rel="icon" href="icons/16.png" sizes="16x16">
+<link rel="apple-touch-icon" href="/apple.png">
+<link rel="manifest" href="/site.webmanifest">
+<meta name="theme-color" content="#112233" media="(prefers-color-scheme: light)">
+<meta name="theme-color" content="#000000" media="(prefers-color-scheme: dark)">
+</head></html>`))
+		case "/site.webmanifest":
+			w.Write([]byte(`{"name": "Board Game Geek", "short_name": "BGG", "theme_color": "#ffffff", "icons": [
+				{"src": "/icons/maskable.png", "sizes": "1024x1024", "purpose": "maskable"},
+				{"src": "icons/512.png", "sizes": "192x192 512x512"}
+			]}`))
+		case "/bare":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Nothing Here</title></head></html>`))
+		}
+	}))
+	defer server.Close()
+
+	info, err := (&ScraperGeneric{}).Scrape(server.URL + "/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectm.CheckJSON(info, &expectm.ExpectedM{
+		"SiteName":    "Board Game Geek",
+		"SiteIconURL": server.URL + "/icons/512.png",
+		"ThemeColor":  "#112233",
+	}); err != nil {
+		t.Error(err)
+	}
+
+	// Without the manifest the apple touch icon is the biggest
+	info, err = (&ScraperGeneric{SkipManifest: true}).Scrape(server.URL + "/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.SiteIconURL != server.URL+"/apple.png" {
+		t.Errorf("Expected the apple touch icon but got '%s'", info.SiteIconURL)
+	}
+
+	info, err = (&ScraperGeneric{}).Scrape(server.URL + "/bare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectm.CheckJSON(info, &expectm.ExpectedM{
+		"SiteName":    strings.Split(strings.TrimPrefix(server.URL, "http://"), ":")[0],
+		"SiteIconURL": server.URL + "/favicon.ico",
+		"ThemeColor":  "",
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIconSize(t *testing.T) {
+	sizes := map[string]int{
+		"":            0,
+		"16x16":       16,
+		"16x16 48X48": 48,
+		"any":         1 << 16,
+		"bogus":       0,
+	}
+	for sizes, expected := range sizes {
+		if got := iconSize(sizes); got != expected {
+			t.Errorf("Expected '%s' to be %d but got %d", sizes, expected, got)
+		}
+	}
+}
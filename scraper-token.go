@@ -0,0 +1,102 @@
+package vinscraper
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// Somewhere to keep OAuth tokens between requests
+// Processes that share a store share a token instead of each fetching their own
+type TokenStore interface {
+	Get(key string) (*oauth2.Token, bool)
+	Set(key string, token *oauth2.Token) error
+	Delete(key string) error
+}
+
+// A TokenStore for a single process
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]*oauth2.Token
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{}
+}
+
+func (s *MemoryTokenStore) Get(key string) (*oauth2.Token, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[key]
+	return token, ok
+}
+
+func (s *MemoryTokenStore) Set(key string, token *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokens == nil {
+		s.tokens = make(map[string]*oauth2.Token)
+	}
+	s.tokens[key] = token
+	return nil
+}
+
+func (s *MemoryTokenStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, key)
+	return nil
+}
+
+// Keeps each token as a JSON file in Dir, so processes on the same machine can share them
+type FileTokenStore struct {
+	Dir string
+}
+
+func NewFileTokenStore(dir string) (*FileTokenStore, error) {
+	// Tokens are as good as passwords, only we should be able to read them
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileTokenStore{Dir: dir}, nil
+}
+
+func (s *FileTokenStore) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:])+".token")
+}
+
+func (s *FileTokenStore) Get(key string) (*oauth2.Token, bool) {
+	b, err := ioutil.ReadFile(s.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var token oauth2.Token
+	if err := json.Unmarshal(b, &token); err != nil {
+		return nil, false
+	}
+	return &token, true
+}
+
+func (s *FileTokenStore) Set(key string, token *oauth2.Token) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	// Only we should be able to read tokens
+	return writeFileAtomic(s.path(key), b, 0600)
+}
+
+func (s *FileTokenStore) Delete(key string) error {
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
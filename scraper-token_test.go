@@ -0,0 +1,45 @@
+package vinscraper
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vinscraper-tokens")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileTokenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Get("reddit:app:"); ok {
+		t.Fatal("Expected an empty store")
+	}
+
+	expiry := time.Now().Add(time.Hour).Round(time.Second)
+	if err := store.Set("reddit:app:", &oauth2.Token{AccessToken: "abc", TokenType: "bearer", Expiry: expiry}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process would see it through its own store
+	other := &FileTokenStore{Dir: dir}
+	token, ok := other.Get("reddit:app:")
+	if !ok || token.AccessToken != "abc" || !token.Expiry.Equal(expiry) {
+		t.Errorf("Expected the stored token back but got %+v", token)
+	}
+
+	if err := other.Delete("reddit:app:"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Get("reddit:app:"); ok {
+		t.Error("Expected the token to be deleted")
+	}
+}
@@ -1,8 +1,10 @@
 package vinscraper
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
@@ -23,7 +25,12 @@ var (
 	ErrTwitterCantFindLinkId   = errors.New("could not find tweet id in link")
 )
 
-var tweetUrlRegexp = "twitter\\.com\\/.*\\/status(?:es)?\\/([^\\/\\?]+)"
+const (
+	TwitterIconURL    = "https://abs.twimg.com/favicons/twitter.ico"
+	TwitterThemeColor = "#1DA1F2"
+)
+
+var tweetUrlRegexp = "(?:twitter|x)\\.com\\/.*\\/status(?:es)?\\/([^\\/\\?]+)"
 
 type TwitterTweetMeta struct {
 	AuthorAvatar     string
@@ -39,15 +46,32 @@ type TwitterTweetMeta struct {
 type TwitterScraper struct {
 	ConsumerKey    string
 	ConsumerSecret string
+	HTTPClient     *http.Client // Overrides the client from Scraping
+}
+
+// go-twitter doesn't take a context on its calls, so this attaches
+// one to every request the client sends
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
 }
 
 func (ts *TwitterScraper) NewClient() (*twitter.Client, error) {
+	return ts.NewClientContext(context.Background())
+}
+
+// The client's requests are tied to ctx, so cancelling it stops any API call in progress
+func (ts *TwitterScraper) NewClientContext(ctx context.Context) (*twitter.Client, error) {
 	if ts.ConsumerKey == "" {
-		return nil, ErrTwitterNoConsumerKey
+		return nil, newScrapeError(SourceNameTwitter, ErrorKindAuth, ErrTwitterNoConsumerKey)
 	}
 
 	if ts.ConsumerSecret == "" {
-		return nil, ErrTwitterNoConsumerSecret
+		return nil, newScrapeError(SourceNameTwitter, ErrorKindAuth, ErrTwitterNoConsumerSecret)
 	}
 
 	// oauth2 configures a client that uses app credentials to keep a fresh token
@@ -57,7 +81,11 @@ func (ts *TwitterScraper) NewClient() (*twitter.Client, error) {
 		TokenURL:     "https://api.twitter.com/oauth2/token",
 	}
 	// http.Client will automatically authorize Requests
-	httpClient := config.Client(oauth2.NoContext)
+	httpClient := config.Client(oauth2Context(ctx, ts.HTTPClient))
+	httpClient.Transport = &contextTransport{
+		ctx:  ctx,
+		base: httpClient.Transport,
+	}
 
 	// Twitter client
 	client := twitter.NewClient(httpClient)
@@ -66,9 +94,12 @@ func (ts *TwitterScraper) NewClient() (*twitter.Client, error) {
 
 func (ts *TwitterScraper) GetLinkTweetId(link string) (id int64, ok bool) {
 	ok = false
-	u, _ := url.Parse(link)
+	u, err := url.Parse(link)
+	if err != nil {
+		return
+	}
 	host := strings.ToLower(u.Hostname())
-	if !strings.HasSuffix(host, "twitter.com") {
+	if !strings.HasSuffix(host, "twitter.com") && host != "x.com" && !strings.HasSuffix(host, ".x.com") {
 		return
 	}
 	r, err := regexp.Compile(tweetUrlRegexp)
@@ -90,25 +121,84 @@ func (ts *TwitterScraper) GetLinkTweetId(link string) (id int64, ok bool) {
 	return
 }
 
+// mobile.twitter.com, x.com and links with the wrong screen name in them all point at the same tweet
+// The /i/ path works for every tweet so we don't need to know who posted it
+func (ts *TwitterScraper) StandardizeURL(link string) string {
+	id, ok := ts.GetLinkTweetId(link)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("https://twitter.com/i/status/%d", id)
+}
+
 func (ts *TwitterScraper) WantsURL(link string) bool {
 	_, ok := ts.GetLinkTweetId(link)
 	return ok
 }
 
 func (ts *TwitterScraper) Scrape(link string) (*ScrapeInfo, error) {
-	client, err := ts.NewClient()
+	return ts.ScrapeContext(context.Background(), link)
+}
+
+func (ts *TwitterScraper) ScrapeContext(ctx context.Context, link string) (*ScrapeInfo, error) {
+	info, err := ts.scrape(ctx, link)
+	if err != nil {
+		return nil, wrapScrapeError(SourceNameTwitter, err)
+	}
+	return info, nil
+}
+
+// Twitter's error codes say more than the status does
+// https://developer.twitter.com/en/support/twitter-api/error-troubleshooting
+func twitterError(resp *http.Response, err error) error {
+	var tokenErr *oauth2.RetrieveError
+	if errors.As(err, &tokenErr) && tokenErr.Response != nil {
+		return httpStatusError(SourceNameTwitter, tokenErr.Response.StatusCode, err)
+	}
+	if resp == nil {
+		return wrapScrapeError(SourceNameTwitter, err)
+	}
+	if err == nil {
+		err = errors.New(resp.Status)
+	}
+
+	se := httpResponseError(SourceNameTwitter, resp, err)
+	var apiErr twitter.APIError
+	if errors.As(err, &apiErr) {
+		for _, detail := range apiErr.Errors {
+			switch detail.Code {
+			case 88:
+				se.Kind = ErrorKindRateLimited
+			case 32, 89, 215:
+				se.Kind = ErrorKindAuth
+			case 8, 34, 144:
+				se.Kind = ErrorKindNotFound
+			case 63, 179:
+				se.Kind = ErrorKindBlocked
+			}
+		}
+		se.Retryable = se.Kind == ErrorKindRateLimited || se.Kind == ErrorKindTransient
+	}
+	return se
+}
+
+func (ts *TwitterScraper) scrape(ctx context.Context, link string) (*ScrapeInfo, error) {
+	client, err := ts.NewClientContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	id, ok := ts.GetLinkTweetId(link)
 	if !ok {
-		return nil, ErrTwitterCantFindLinkId
+		return nil, newScrapeError(SourceNameTwitter, ErrorKindInvalid, ErrTwitterCantFindLinkId)
 	}
 
-	tweet, _, err := client.Statuses.Show(id, &twitter.StatusShowParams{
+	tweet, resp, err := client.Statuses.Show(id, &twitter.StatusShowParams{
 		TweetMode: "extended",
 	})
+	if err != nil || resp.StatusCode >= 400 {
+		return nil, twitterError(resp, err)
+	}
 
 	thumbnail := ""
 
@@ -131,6 +221,9 @@ func (ts *TwitterScraper) Scrape(link string) (*ScrapeInfo, error) {
 		SourceType:       SourceTwitterTweet,
 		Title:            `Tweet by ` + tweet.User.ScreenName,
 		ThumbnailSources: []string{thumbnail},
+		SiteName:         "Twitter",
+		SiteIconURL:      TwitterIconURL,
+		ThemeColor:       TwitterThemeColor,
 		Meta: &TwitterTweetMeta{
 			AuthorScreenName: tweet.User.ScreenName,
 			AuthorName:       tweet.User.Name,
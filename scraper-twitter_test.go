@@ -28,6 +28,7 @@ func TestScrapeTwitterWants(t *testing.T) {
 	tests := CreateWantTests(scraper, []string{
 		"https://twitter.com/Cephalofair/status/1328452020060254210",
 		"https://twitter.com/Cephalofair/status/1328452020060254210?stuff=tre#whatever",
+		"https://x.com/Cephalofair/status/1328452020060254210",
 	}, []string{
 		"https://wordpress.org/showcase/ladybird-education/",
 		"https://google.com",
@@ -0,0 +1,86 @@
+package vinscraper
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Scrapers that know how their urls are put together can turn every variation
+// of a link into one canonical url without having to fetch anything
+type URLStandardizer interface {
+	// Returns "" if the link isn't one this scraper understands
+	StandardizeURL(link string) string
+}
+
+// The order matters, the generic scraper standardizes everything so it goes last
+var builtinStandardizers = []URLStandardizer{
+	&RedditScraper{},
+	&TwitterScraper{},
+	&YouTubeScraper{},
+	&ScraperGeneric{},
+}
+
+// Gives the same StandardizedURL the built in scrapers would, without doing a network fetch
+// This is meant for deduplicating links before scraping them. The one exception is the generic
+// scraper, which can only find a page's <link rel=canonical> after fetching it.
+// Links that can't be parsed come back unchanged
+func StandardizeURL(link string) string {
+	for _, s := range builtinStandardizers {
+		if standard := s.StandardizeURL(link); standard != "" {
+			return standard
+		}
+	}
+	return link
+}
+
+// Like the package level StandardizeURL but asks this Scraping's scrapers first
+func (s *Scraping) StandardizeURL(link string) string {
+	for _, r := range s.EnabledScrapers() {
+		if !r.Scraper.WantsURL(link) {
+			continue
+		}
+		if std, ok := r.Scraper.(URLStandardizer); ok {
+			if standard := std.StandardizeURL(link); standard != "" {
+				return standard
+			}
+		}
+		break
+	}
+	return StandardizeURL(link)
+}
+
+// Lowercases the scheme and host, drops default ports, fragments and utm_* tracking
+// parameters and sorts whatever query parameters are left
+func standardizeGenericURL(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.RawQuery = stripTrackingParams(u.Query()).Encode()
+
+	return u.String()
+}
+
+func stripTrackingParams(query url.Values) url.Values {
+	for k := range query {
+		if strings.HasPrefix(strings.ToLower(k), "utm_") {
+			query.Del(k)
+		}
+	}
+	return query
+}
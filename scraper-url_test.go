@@ -0,0 +1,68 @@
+package vinscraper
+
+import "testing"
+
+func TestStandardizeURL(t *testing.T) {
+	tests := map[string][]string{
+		"https://www.youtube.com/watch?v=DP0t2MmOMEA": {
+			"https://youtu.be/DP0t2MmOMEA",
+			"https://youtube.com/watch?v=DP0t2MmOMEA",
+			"https://www.youtube.com/watch?v=DP0t2MmOMEA&t=432s",
+		},
+		"https://www.reddit.com/comments/jn78c5/_/": {
+			"https://www.reddit.com/r/boardgames/comments/jn78c5/the_3_minute_board_games_top_100_games_2020/",
+			"https://old.reddit.com/r/boardgames/comments/jn78c5/the_3_minute_board_games_top_100_games_2020/?utm_source=share",
+			"https://np.reddit.com/r/boardgames/comments/jn78c5/the_3_minute_board_games_top_100_games_2020/",
+			"https://www.reddit.com/r/boardgames/comments/jn78c5/_/",
+			"https://www.reddit.com/r/boardgames/comments/jn78c5/?sort=new",
+			"https://www.reddit.com/r/boardgames/comments/jn78c5/fake_post/?share_id=x",
+			"https://www.reddit.com/r/boardgames/comments/jn78c5",
+			"https://redd.it/jn78c5",
+		},
+		"https://www.reddit.com/comments/jnaol2/_/gb077ru/": {
+			"https://old.reddit.com/r/Warhammer40k/comments/jnaol2/my_halloween_costume_made_in_3_days_salamander/gb077ru?utm_source=share&utm_medium=web2x&context=3",
+			"https://new.reddit.com/r/Warhammer40k/comments/jnaol2/my_halloween_costume_made_in_3_days_salamander/gb077ru/?context=3",
+			"https://www.reddit.com/comments/jnaol2/_/gb077ru",
+			"https://www.reddit.com/r/Warhammer40k/comments/JNAOL2/my_halloween_costume/GB077RU/",
+		},
+		"https://www.reddit.com/r/boardgames/": {
+			"https://old.reddit.com/r/boardgames",
+			"https://www.reddit.com/r/BoardGames/top/?t=year",
+		},
+		"https://www.reddit.com/user/someone/": {
+			"https://www.reddit.com/u/someone",
+			"https://old.reddit.com/user/someone/submitted/",
+		},
+		"https://www.reddit.com/r/boardgames/wiki/index/": {
+			"https://www.reddit.com/r/boardgames/wiki",
+			"https://old.reddit.com/r/boardgames/wiki/index",
+		},
+		"https://twitter.com/i/status/1328452020060254210": {
+			"https://twitter.com/Cephalofair/status/1328452020060254210",
+			"https://mobile.twitter.com/Cephalofair/status/1328452020060254210?s=20",
+			"https://x.com/Cephalofair/status/1328452020060254210",
+		},
+		"https://example.com/some/page?a=1&b=2": {
+			"HTTPS://Example.com:443/some/page?b=2&a=1#comments",
+			"https://example.com/some/page?a=1&utm_campaign=x&b=2",
+		},
+		// Only look like reddit, so they're standardized like any other site
+		"https://notreddit.com/r/boardgames": {
+			"https://notreddit.com/r/boardgames",
+		},
+		"https://www.evilreddit.com/r/boardgames/comments/jn78c5/x/": {
+			"https://www.evilreddit.com/r/boardgames/comments/jn78c5/x/?utm_source=share",
+		},
+		"not a url": {
+			"not a url",
+		},
+	}
+
+	for expected, links := range tests {
+		for _, link := range links {
+			if standard := StandardizeURL(link); standard != expected {
+				t.Errorf("Expected %s to standardize to %s but got %s", link, expected, standard)
+			}
+		}
+	}
+}
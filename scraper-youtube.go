@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
 	"regexp"
@@ -15,6 +16,7 @@ import (
 	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/youtube/v3"
 )
 
@@ -25,6 +27,9 @@ var (
 
 const (
 	SourceYouTubeVideo SourceType = "youtube_video"
+
+	YouTubeIconURL    = "https://www.youtube.com/favicon.ico"
+	YouTubeThemeColor = "#FF0000"
 )
 
 type YouTubeVideoMeta struct {
@@ -33,6 +38,7 @@ type YouTubeVideoMeta struct {
 }
 
 type YouTubeScraper struct {
+	HTTPClient  *http.Client // Overrides the client from Scraping
 	OAuthConfig *oauth2.Config
 	OAuthToken  *oauth2.Token
 }
@@ -66,9 +72,11 @@ func LoadYouTubeScraper(configFile string, tokenFile string) (*YouTubeScraper, e
 }
 
 func (yt *YouTubeScraper) GetService() (*youtube.Service, error) {
-	ctx := context.Background()
+	return yt.GetServiceContext(context.Background())
+}
 
-	client := yt.OAuthConfig.Client(ctx, yt.OAuthToken)
+func (yt *YouTubeScraper) GetServiceContext(ctx context.Context) (*youtube.Service, error) {
+	client := yt.OAuthConfig.Client(oauth2Context(ctx, yt.HTTPClient), yt.OAuthToken)
 	service, err := youtube.New(client)
 	return service, err
 }
@@ -86,26 +94,74 @@ func (yt *YouTubeScraper) WantsURL(link string) bool {
 	return false
 }
 
+func (yt *YouTubeScraper) StandardizeURL(link string) string {
+	if !yt.WantsURL(link) {
+		return ""
+	}
+	return "https://www.youtube.com/watch?v=" + GetLinkYouTubeVideoId(link)
+}
+
 func (yt *YouTubeScraper) Scrape(link string) (*ScrapeInfo, error) {
+	return yt.ScrapeContext(context.Background(), link)
+}
+
+func (yt *YouTubeScraper) ScrapeContext(ctx context.Context, link string) (*ScrapeInfo, error) {
+	info, err := yt.scrape(ctx, link)
+	if err != nil {
+		return nil, youTubeError(err)
+	}
+	return info, nil
+}
+
+// Google puts why a request failed in the reasons of its errors
+// Running out of the daily quota won't fix itself by retrying, hitting the rate limit will
+func youTubeError(err error) error {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		var tokenErr *oauth2.RetrieveError
+		if errors.As(err, &tokenErr) && tokenErr.Response != nil {
+			return httpStatusError(SourceNameYouTube, tokenErr.Response.StatusCode, err)
+		}
+		return wrapScrapeError(SourceNameYouTube, err)
+	}
+
+	se := httpStatusError(SourceNameYouTube, apiErr.Code, err)
+	if apiErr.Header != nil {
+		se.RetryAfter = retryAfter(apiErr.Header, time.Now())
+	}
+	for _, item := range apiErr.Errors {
+		switch item.Reason {
+		case "quotaExceeded", "dailyLimitExceeded":
+			se.Kind = ErrorKindRateLimited
+			se.Retryable = false
+		case "rateLimitExceeded", "userRateLimitExceeded":
+			se.Kind = ErrorKindRateLimited
+			se.Retryable = true
+		}
+	}
+	return se
+}
+
+func (yt *YouTubeScraper) scrape(ctx context.Context, link string) (*ScrapeInfo, error) {
 	id := GetLinkYouTubeVideoId(link)
 	if id == "" {
-		return nil, ErrNoYouTubeId
+		return nil, newScrapeError(SourceNameYouTube, ErrorKindInvalid, ErrNoYouTubeId)
 	}
 
-	service, err := yt.GetService()
+	service, err := yt.GetServiceContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	call := service.Videos.List([]string{"contentDetails", "snippet"})
-	call = call.Id(id)
+	call = call.Id(id).Context(ctx)
 	list, err := call.Do()
 	if err != nil {
 		return nil, err
 	}
 
 	if len(list.Items) == 0 {
-		return nil, errors.Wrap(ErrVideoNotFound, fmt.Sprintf("ID: '%s'", id))
+		return nil, newScrapeError(SourceNameYouTube, ErrorKindNotFound, errors.Wrap(ErrVideoNotFound, fmt.Sprintf("ID: '%s'", id)))
 	}
 
 	snip := list.Items[0].Snippet
@@ -124,6 +180,10 @@ func (yt *YouTubeScraper) Scrape(link string) (*ScrapeInfo, error) {
 		SourceType: SourceYouTubeVideo,
 		SourceKey:  id,
 		Title:      snip.Title,
+
+		SiteName:    "YouTube",
+		SiteIconURL: YouTubeIconURL,
+		ThemeColor:  YouTubeThemeColor,
 	}
 
 	if snip.Thumbnails.Default != nil {
@@ -1,54 +1,135 @@
 package vinscraper
 
 import (
+	"context"
 	"errors"
+	"net/http"
 	"net/url"
+	"reflect"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
 	ErrNoConsumingScaper = errors.New("no scraper wanted to consume that url")
-	ErrSourceInvalidURL    = errors.New("Invalid URL provided")
+	ErrSourceInvalidURL  = errors.New("Invalid URL provided")
 )
 
 type SourceType string
 
 type ScrapeReplacer struct {
-	URLMatches  string // Will turn into regex. If the url matches, it will replace whatever matches FindMatches with ReplaceWith
-	FindMatches string
-	ReplaceWith string
+	URLMatches  string      // Will turn into regex. If the url matches, it will replace whatever matches FindMatches with ReplaceWith. Blank matches every url
+	FindMatches string      // Also a regex
+	ReplaceWith string      // Can use capture groups from FindMatches, EG: $1 or ${name}
+	Field       ScrapeField // Ignored by TitleReplacers and DescriptionReplacers
+	SourceType  SourceType  // If set, only results of this type are replaced
+
+	urlRE  *regexp.Regexp
+	findRE *regexp.Regexp
 }
 
 type Scraping struct {
-	Scrapers             []Scraper
+	// Optional, results are cached by their standardized url
+	Cache Cache
+	// Time a result stays fresh in the Cache by its SourceType, falls back to DefaultCacheTTL
+	CacheTTL        map[SourceType]time.Duration
+	DefaultCacheTTL time.Duration
+	// How long past its TTL a result will be served while it's refreshed in the background
+	CacheStaleTTL time.Duration
+	// How long a failed scrape is remembered so dead links aren't retried over and over
+	// Failures that might work on a retry, like 429s, 5xxs and network errors, aren't remembered
+	CacheErrorTTL time.Duration
+	// How long a background refresh of a stale result gets, falls back to DefaultCacheRefreshTimeout
+	CacheRefreshTimeout time.Duration
+	// Max number of links ScrapeBatch and ScrapeStream work on at once
+	// Links a BatchingScraper batches don't count, they share requests instead
+	Concurrency int
+	// Optional, every request the built in scrapers make waits on this, EG: NewHostRateLimiter(5, 5)
+	RateLimiter *HostRateLimiter
+	// Optional, retries scrapes that fail with a retryable ScrapeError
+	Retry *RetryPolicy
+	// When the scraper that wants a link fails, try the next ones that want it instead of giving up
+	// EG: get the generic OpenGraph result when the YouTube quota runs out
+	Fallback bool
+	// Max number of links from the same host being scraped at once, shared by all batches
+	// Links a BatchingScraper batches don't count either
+	HostConcurrency int
+	// If set, every built in scraper makes its requests with this client unless it has its own
+	// Use it for proxies, timeouts, custom TLS or test doubles
+	HTTPClient *http.Client
+	// Add to these with AddReplacer so they're compiled once, ones appended directly work
+	// but are compiled on every scrape
+	Replacers []*ScrapeReplacer
+	// Unnamed scrapers, tried in order before the registered ones, catch all scrapers still go last
+	// A registered scraper of the same type as one of these is left out, so setting
+	// Scrapers: []Scraper{&RedditScraper{UserAgent: "..."}} replaces the default reddit scraper
+	// Ones that are registered too, like the defaults NewScraping lists here, go by the registry,
+	// so taking them out of here doesn't stop them, Disable does
+	// Prefer Register so they can be looked up, enabled and disabled by name
+	Scrapers []Scraper
+	// Deprecated: use AddReplacer with FieldTitle or FieldDescription, these are compiled on every scrape
 	TitleReplacers       []ScrapeReplacer
 	DescriptionReplacers []ScrapeReplacer
+
+	registryMu sync.RWMutex
+	registry   []*RegisteredScraper
+
+	mu         sync.Mutex
+	hostSlots  *hostSemaphores
+	refreshing map[string]bool
 }
 
+// The defaults are registered as reddit and generic and listed in Scrapers like they always were,
+// so code that changes Scrapers keeps working
 func NewScraping() *Scraping {
-	return &Scraping{
-		Scrapers: []Scraper{
-			&RedditScraper{},
-			&ScraperGeneric{},
-		},
-		TitleReplacers: []ScrapeReplacer{
-		},
+	reddit, generic := &RedditScraper{}, &ScraperGeneric{}
+	s := &Scraping{
+		Scrapers:       []Scraper{reddit, generic},
+		TitleReplacers: []ScrapeReplacer{},
 	}
+	s.Register("reddit", PriorityDefault, reddit)
+	s.Register("generic", PriorityDefault, generic)
+	return s
 }
 
 type ScrapeInfo struct {
-	CreditURL       string
-	CreditTitle     string
-	Description     string
-	Meta interface{}
-	SourceKey string // a unique identifier for that source type. EG: reddit thing id, youtube video id, twitch channel name
-	SourceType      SourceType
+	CreditURL        string
+	CreditTitle      string
+	Description      string
+	Meta             interface{}
+	SourceKey        string // a unique identifier for that source type. EG: reddit thing id, youtube video id, twitch channel name
+	SourceType       SourceType
 	ThumbnailSources []string
-	Title           string
-	URL string
-	// TODO: Add logic to consolidate URL into a StandardizedURL so that youtu.be/123 and youtube.com/watch?v=123 and www.youtube.com/watch?v=123 all
-	//  end up with the same StandardizedURL
+	Thumbnails       []Thumbnail // The same images as ThumbnailSources, with their size and alt text when the source says
+	Title            string
+	URL              string
+	// youtu.be/123, youtube.com/watch?v=123 and www.youtube.com/watch?v=123 all end up with the same StandardizedURL
+	StandardizedURL string
+	// The site the link is on, for showing next to the title
+	SiteName    string
+	SiteIconURL string
+	ThemeColor  string // Whatever CSS color the site gave, usually like #ff4500
+	// HTML for embedding the link's video, audio or post, from an oEmbed provider in the registry
+	EmbedHTML string
+	// Name of the scraper that produced this
+	ScrapedBy string
+	// Scrapers that failed before ScrapedBy succeeded, only when Scraping.Fallback is on
+	FallbackErrors []FallbackError
+}
+
+type Thumbnail struct {
+	URL    string
+	Width  int    `json:",omitempty"`
+	Height int    `json:",omitempty"`
+	Alt    string `json:",omitempty"`
+}
+
+type FallbackError struct {
+	Scraper string
+	Message string
+	Err     error `json:"-"`
 }
 
 type Scraper interface {
@@ -58,38 +139,132 @@ type Scraper interface {
 	Scrape(url string) (*ScrapeInfo, error)
 }
 
-func ScrapeReplace(link string, field *string, replaces []ScrapeReplacer) error {
-	for _, v := range replaces {
-		urlRE, err := regexp.Compile(link)
-		if err != nil {
-			return err
-		}
-		if urlRE.MatchString(link) {
-			*field = strings.ReplaceAll(*field, v.FindMatches, v.ReplaceWith)
-		}
+// A Scraper that can be cancelled or given a deadline through a context
+// All of the built in scrapers are ContextScrapers
+type ContextScraper interface {
+	Scraper
+	ScrapeContext(ctx context.Context, url string) (*ScrapeInfo, error)
+}
+
+// Runs the scraper with the context if it supports one. Scrapers that don't
+// can't be stopped, so we stop waiting on them once the context is done
+func scrapeContext(ctx context.Context, s Scraper, link string) (*ScrapeInfo, error) {
+	if cs, ok := s.(ContextScraper); ok {
+		return cs.ScrapeContext(ctx, link)
 	}
-	return nil
+
+	type result struct {
+		info *ScrapeInfo
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		info, err := s.Scrape(link)
+		done <- result{info, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-done:
+		return r.info, r.err
+	}
+}
+
+// The scraper's type name, EG: RedditScraper
+// Used as the name of scrapers that aren't registered
+func ScraperName(s Scraper) string {
+	name := reflect.TypeOf(s).String()
+	return name[strings.LastIndex(name, ".")+1:]
 }
 
 func (s *Scraping) Scrape(link string) (*ScrapeInfo, error) {
+	return s.ScrapeContext(context.Background(), link)
+}
+
+// Scrapes the link, giving up and returning ctx.Err() once the context is done
+func (s *Scraping) ScrapeContext(ctx context.Context, link string) (*ScrapeInfo, error) {
 	_, err := url.ParseRequestURI(link)
 	if err != nil {
 		return nil, ErrSourceInvalidURL
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if s.Cache != nil {
+		return s.cachedScrape(ctx, link)
+	}
+	return s.scrape(ctx, link)
+}
+
+func (s *Scraping) scrape(ctx context.Context, link string) (*ScrapeInfo, error) {
+	if s.HTTPClient != nil {
+		ctx = WithHTTPClient(ctx, s.HTTPClient)
+	}
+	if s.RateLimiter != nil {
+		ctx = withRateLimiter(ctx, s.RateLimiter)
+	}
+
 	var item *ScrapeInfo
-	for _, v := range s.Scrapers {
-		if v.WantsURL(link) {
-			item, err = v.Scrape(link)
-			if err != nil {
-				return nil, err
-			}
+	var failures []FallbackError
+	for _, r := range s.EnabledScrapers() {
+		if !r.Scraper.WantsURL(link) {
+			continue
+		}
+		var info *ScrapeInfo
+		var err error
+		if s.Retry != nil {
+			info, err = s.Retry.scrape(ctx, r.Scraper, link)
+		} else {
+			info, err = scrapeContext(ctx, r.Scraper, link)
+		}
+		// Nothing came back, so as far as we're concerned the scraper didn't take the url
+		if err == nil && info == nil {
+			err = ErrNoConsumingScaper
+		}
+		if err == nil {
+			item = info
+			item.ScrapedBy = r.Name
 			break
 		}
+		// Whatever went wrong was most likely caused by the cancellation
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if !s.Fallback {
+			return nil, err
+		}
+		failures = append(failures, FallbackError{
+			Scraper: r.Name,
+			Message: err.Error(),
+			Err:     err,
+		})
 	}
 	if item == nil {
+		// The preferred scraper's error is the one the caller would have gotten without Fallback
+		if len(failures) > 0 {
+			return nil, failures[0].Err
+		}
 		return nil, ErrNoConsumingScaper
 	}
+	item.FallbackErrors = failures
+
+	// Most scrapers only know their thumbnails' urls
+	if len(item.Thumbnails) == 0 {
+		for _, src := range item.ThumbnailSources {
+			if src != "" {
+				item.Thumbnails = append(item.Thumbnails, Thumbnail{URL: src})
+			}
+		}
+	}
+
+	for _, r := range s.Replacers {
+		if err := r.Apply(link, item); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := ScrapeReplace(link, &item.Title, s.TitleReplacers); err != nil {
 		return nil, err
@@ -100,6 +275,9 @@ func (s *Scraping) Scrape(link string) (*ScrapeInfo, error) {
 	}
 
 	item.URL = link
+	if item.StandardizedURL == "" {
+		item.StandardizedURL = s.StandardizeURL(link)
+	}
 
 	return item, nil
 }
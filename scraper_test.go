@@ -1,6 +1,8 @@
 package vinscraper
 
 import (
+	"testing"
+
 	"github.com/monstercat/golib/expectm"
 	"github.com/pkg/errors"
 )
@@ -25,7 +27,7 @@ func (st *ScrapeTest) Run() error {
 
 	if st.ExpectedM != nil || st.ExpectedError != nil {
 		res, err := st.Scraper.Scrape(st.URL)
-		if err != st.ExpectedError {
+		if !errors.Is(err, st.ExpectedError) {
 			if st.ExpectedError == nil {
 				return errors.Errorf("Expected no error but got '%s'", err)
 			}
@@ -78,3 +80,68 @@ func ApplyScraperTests(scraper Scraper, tests []*ScrapeTest) []*ScrapeTest {
 	}
 	return tests
 }
+
+type failingScraper struct {
+	err error
+}
+
+func (f *failingScraper) WantsURL(link string) bool {
+	return true
+}
+
+func (f *failingScraper) Scrape(link string) (*ScrapeInfo, error) {
+	return nil, f.err
+}
+
+func TestScrapingFallback(t *testing.T) {
+	quotaErr := errors.New("quota exceeded")
+	scraping := &Scraping{
+		Scrapers: []Scraper{
+			&failingScraper{quotaErr},
+			&staticScraper{ScrapeInfo{Title: "From OpenGraph"}},
+		},
+	}
+
+	if _, err := scraping.Scrape("https://example.com/"); err != quotaErr {
+		t.Errorf("Expected '%s' without fallback but got '%v'", quotaErr, err)
+	}
+
+	scraping.Fallback = true
+	info, err := scraping.Scrape("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "From OpenGraph" || info.ScrapedBy != "staticScraper" {
+		t.Errorf("Expected the second scraper's result but got %+v", info)
+	}
+	if len(info.FallbackErrors) != 1 || info.FallbackErrors[0].Scraper != "failingScraper" || info.FallbackErrors[0].Err != quotaErr {
+		t.Errorf("Expected the first scraper's error to be recorded but got %+v", info.FallbackErrors)
+	}
+
+	scraping.Scrapers = scraping.Scrapers[:1]
+	if _, err := scraping.Scrape("https://example.com/"); err != quotaErr {
+		t.Errorf("Expected '%s' when every scraper fails but got '%v'", quotaErr, err)
+	}
+}
+
+func TestScrapingNothingBack(t *testing.T) {
+	scraping := &Scraping{
+		Scrapers: []Scraper{
+			&failingScraper{},
+			&staticScraper{ScrapeInfo{Title: "From OpenGraph"}},
+		},
+	}
+
+	if _, err := scraping.Scrape("https://example.com/"); err != ErrNoConsumingScaper {
+		t.Errorf("Expected '%s' without fallback but got '%v'", ErrNoConsumingScaper, err)
+	}
+
+	scraping.Fallback = true
+	info, err := scraping.Scrape("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ScrapedBy != "staticScraper" || len(info.FallbackErrors) != 1 || info.FallbackErrors[0].Err != ErrNoConsumingScaper {
+		t.Errorf("Expected the second scraper's result after the first returned nothing but got %+v", info)
+	}
+}